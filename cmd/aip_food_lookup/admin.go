@@ -0,0 +1,540 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+const (
+	adminPathPrefix            = "/admin/"
+	adminSuggestionsPath       = "/admin/suggestions"
+	adminSuggestionsAcceptPath = "/admin/suggestions/accept"
+	adminSuggestionsRejectPath = "/admin/suggestions/reject"
+	adminDisputesPath          = "/admin/disputes"
+	adminDisputesAcceptPath    = "/admin/disputes/accept"
+	adminDisputesRejectPath    = "/admin/disputes/reject"
+	adminSinksPath             = "/admin/sinks"
+	adminFeedbackPath          = "/admin/feedback"
+	adminFeedbackHandlePath    = "/admin/feedback/handle"
+	adminSearchPath            = "/admin/search"
+)
+
+var (
+	catalogCategoryPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+	catalogWriteLock       sync.Mutex
+)
+
+type adminSuggestionListResponse struct {
+	Suggestions []suggestionRecord `json:"suggestions"`
+}
+
+type adminDisputeListResponse struct {
+	Disputes []suggestionRecord `json:"disputes"`
+}
+
+// adminSuggestionDecision names the suggestion or dispute to accept or
+// reject. For suggestions, Status overrides the proposed status, Category
+// defaults to the most proposed category hint, and Name sets the displayed
+// catalog name. For disputes, Category picks the file the food moves to.
+type adminSuggestionDecision struct {
+	Text     string `json:"text"`
+	Category string `json:"category"`
+	Status   string `json:"status"`
+	Name     string `json:"name"`
+}
+
+type adminSinkListResponse struct {
+	Sinks []sinkStats `json:"sinks"`
+}
+
+// adminSearchResponse is a /search result together with the thresholds it
+// was matched with.
+type adminSearchResponse struct {
+	Options    foodcatalog.MatchOptions `json:"options"`
+	Allowed    []string                 `json:"allowed"`
+	NotAllowed []string                 `json:"not_allowed"`
+}
+
+type adminFeedbackListResponse struct {
+	Feedback []feedbackRecord `json:"feedback"`
+}
+
+// adminFeedbackDecision marks feedback handled, or reopens it when Handled is
+// false. Note is kept with the handled status.
+type adminFeedbackDecision struct {
+	ID      string `json:"id"`
+	Handled *bool  `json:"handled"`
+	Note    string `json:"note"`
+}
+
+type adminFeedbackResponse struct {
+	OK       bool            `json:"ok"`
+	Feedback *feedbackRecord `json:"feedback,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+type adminSuggestionResponse struct {
+	OK         bool              `json:"ok"`
+	Suggestion *suggestionRecord `json:"suggestion,omitempty"`
+	Foods      int               `json:"foods,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// adminSuggestionsHandler lists suggestions by moderation state, optionally
+// only those with votes for both statuses.
+func adminSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	if suggestions, ok := listAdminRecords(w, r, getStore().suggestions); ok {
+		writeAdminJSON(w, http.StatusOK, adminSuggestionListResponse{Suggestions: suggestions})
+	}
+}
+
+// adminDisputesHandler lists suggestions that claim the opposite status of an
+// existing catalog food, most disputed first.
+func adminDisputesHandler(w http.ResponseWriter, r *http.Request) {
+	if disputes, ok := listAdminRecords(w, r, getStore().disputes); ok {
+		writeAdminJSON(w, http.StatusOK, adminDisputeListResponse{Disputes: disputes})
+	}
+}
+
+// adminSinksHandler reports delivery counters for every feedback and
+// suggestion sink since the process started.
+func adminSinksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	currentStore := getStore()
+	sinks := []sinkStats{}
+	for _, sink := range []any{currentStore.feedbackSink, currentStore.suggestionSink} {
+		if chain, ok := sink.(*sinkChain); ok {
+			sinks = append(sinks, chain.stats()...)
+		}
+	}
+	writeAdminJSON(w, http.StatusOK, adminSinkListResponse{Sinks: sinks})
+}
+
+// adminSearchHandler runs /search with the configured matching thresholds,
+// each overridable by a query parameter of the same name, so admins can try
+// settings without a restart. Admin searches are not written to the search log.
+func adminSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	values := r.URL.Query()
+	key := strings.TrimSpace(values.Get("key"))
+	if key == "" {
+		http.Error(w, "Key parameter is missing", http.StatusBadRequest)
+		return
+	}
+	currentStore := getStore()
+	options := currentStore.matchOptions
+	for name, field := range map[string]*int{
+		"shortQueryLength": &options.ShortQueryLength,
+		"shortDistance":    &options.ShortDistance,
+		"longDistance":     &options.LongDistance,
+		"soundBonus":       &options.SoundBonus,
+		"minTokenLength":   &options.MinTokenLength,
+	} {
+		if value := values.Get(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, name+" must be a number", http.StatusBadRequest)
+				return
+			}
+			*field = parsed
+		}
+	}
+	if err := options.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lang := currentStore.requestLanguage(r)
+	result := currentStore.matchWith(options, key, values.Get("type"), lang)
+	setLanguageHeaders(w, lang)
+	writeAdminJSON(w, http.StatusOK, adminSearchResponse{Options: options, Allowed: result.Allowed, NotAllowed: result.NotAllowed})
+}
+
+// adminFeedbackHandler lists feedback newest first, filtered by status
+// (default new), source, client, appVersion, a since time, a text query q and
+// a limit.
+func adminFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	values := r.URL.Query()
+	filter := feedbackFilter{
+		Status:     values.Get("status"),
+		Source:     values.Get("source"),
+		Client:     values.Get("client"),
+		AppVersion: values.Get("appVersion"),
+		Query:      values.Get("q"),
+	}
+	if filter.Status == "" {
+		filter.Status = feedbackStatusNew
+	}
+	switch filter.Status {
+	case feedbackStatusNew, feedbackStatusHandled, feedbackStatusAll:
+	default:
+		http.Error(w, "Unknown feedback status", http.StatusBadRequest)
+		return
+	}
+	if since := values.Get("since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, "since must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		filter.Since = parsed
+	}
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = parsed
+	}
+
+	currentStore := getStore()
+	feedback, err := listFeedback(feedbackFilePath(currentStore.dataFolder, currentStore.feedbackPath), filter)
+	if err != nil {
+		writeErrorLog(currentStore.errorLogPath, fmt.Sprintf("feedback list failed: %v", err))
+		http.Error(w, "Feedback store unavailable", http.StatusInternalServerError)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, adminFeedbackListResponse{Feedback: feedback})
+}
+
+// adminHandleFeedbackHandler marks one feedback record handled or new.
+func adminHandleFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var decision adminFeedbackDecision
+	if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+		http.Error(w, "Error decoding JSON", http.StatusBadRequest)
+		return
+	}
+	handled := decision.Handled == nil || *decision.Handled
+
+	currentStore := getStore()
+	filePath := feedbackFilePath(currentStore.dataFolder, currentStore.feedbackPath)
+	record, err := markFeedback(filePath, strings.TrimSpace(decision.ID), handled, strings.TrimSpace(decision.Note), time.Now())
+	if errors.Is(err, errFeedbackNotFound) {
+		writeAdminJSON(w, http.StatusNotFound, adminFeedbackResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		writeErrorLog(currentStore.errorLogPath, fmt.Sprintf("feedback update failed: %v", err))
+		writeAdminJSON(w, http.StatusInternalServerError, adminFeedbackResponse{Error: "feedback store write failed"})
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, adminFeedbackResponse{OK: true, Feedback: &record})
+}
+
+// listAdminRecords applies the shared state and contested filters, writing
+// the error response itself when the request cannot be served.
+func listAdminRecords(w http.ResponseWriter, r *http.Request, records *suggestionStore) ([]suggestionRecord, bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	state := r.URL.Query().Get("state")
+	if state == "" {
+		state = suggestionStatePending
+	}
+	switch state {
+	case suggestionStatePending, suggestionStateAccepted, suggestionStateRejected, suggestionStateAll:
+	default:
+		http.Error(w, "Unknown suggestion state", http.StatusBadRequest)
+		return nil, false
+	}
+
+	result, err := records.list(state, r.URL.Query().Get("contested") == "true")
+	if err != nil {
+		writeErrorLog(getStore().errorLogPath, fmt.Sprintf("suggestion list failed: %v", err))
+		http.Error(w, "Suggestion store unavailable", http.StatusInternalServerError)
+		return nil, false
+	}
+	return result, true
+}
+
+// adminAcceptSuggestionHandler adds a pending suggestion to a catalog
+// category file and reloads the catalog.
+func adminAcceptSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	decision, record, ok := readAdminSuggestionDecision(w, r, getStore().suggestions)
+	if !ok {
+		return
+	}
+
+	currentStore := getStore()
+	status := record.Status
+	if decision.Status != "" {
+		status = decision.Status
+	}
+	if status != suggestionStatusAllowed && status != suggestionStatusNotAllowed {
+		writeAdminJSON(w, http.StatusBadRequest, adminSuggestionResponse{Error: "status must be allowed or not allowed"})
+		return
+	}
+	if _, exists := currentStore.catalogFood(record.Text); exists {
+		writeAdminJSON(w, http.StatusConflict, adminSuggestionResponse{Error: "food is already in the catalog"})
+		return
+	}
+	if decision.Category == "" {
+		decision.Category = categoryFileName(record.topCategoryHint())
+	}
+	catalogPath, err := catalogCategoryPath(currentStore.dataFolder, status, decision.Category)
+	if err != nil {
+		writeAdminJSON(w, http.StatusBadRequest, adminSuggestionResponse{Error: err.Error()})
+		return
+	}
+
+	entry := foodcatalog.CatalogEntry{Name: strings.TrimSpace(decision.Name)}
+	if entry.Name == "" {
+		entry.Name = record.displayName()
+	}
+	if foodcatalog.Fold(entry.Name) != record.Text {
+		entry.Aliases = []string{record.Text}
+	}
+	if err := appendCatalogFood(catalogPath, entry); err != nil {
+		writeErrorLog(currentStore.errorLogPath, fmt.Sprintf("suggestion accept failed: %v", err))
+		writeAdminJSON(w, http.StatusInternalServerError, adminSuggestionResponse{Error: "catalog write failed"})
+		return
+	}
+
+	decided, err := currentStore.suggestions.decide(record.Text, suggestionStateAccepted, status, decision.Category)
+	if err != nil {
+		writeErrorLog(currentStore.errorLogPath, fmt.Sprintf("suggestion store write failed: %v", err))
+	}
+	nextStore, err := reloadFoodStore()
+	if err != nil {
+		writeErrorLog(currentStore.errorLogPath, fmt.Sprintf("catalog reload failed: %v", err))
+		writeAdminJSON(w, http.StatusInternalServerError, adminSuggestionResponse{Suggestion: &decided, Error: "catalog reload failed"})
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, adminSuggestionResponse{OK: true, Suggestion: &decided, Foods: len(nextStore.nameFoods)})
+}
+
+// adminRejectSuggestionHandler marks a pending suggestion as rejected.
+func adminRejectSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	rejectAdminRecord(w, r, getStore().suggestions)
+}
+
+// adminAcceptDisputeHandler moves a disputed catalog food to the opposite
+// status, into the category file given or the one with the same name, and
+// reloads the catalog.
+func adminAcceptDisputeHandler(w http.ResponseWriter, r *http.Request) {
+	decision, record, ok := readAdminSuggestionDecision(w, r, getStore().disputes)
+	if !ok {
+		return
+	}
+
+	currentStore := getStore()
+	food, exists := currentStore.catalogFood(record.Food)
+	if !exists {
+		writeAdminJSON(w, http.StatusConflict, adminSuggestionResponse{Suggestion: &record, Error: "food is no longer in the catalog"})
+		return
+	}
+	status := suggestionStatus(!food.allowed)
+	if decision.Status != "" && decision.Status != status {
+		writeAdminJSON(w, http.StatusBadRequest, adminSuggestionResponse{Error: "status must be " + status})
+		return
+	}
+	if decision.Category == "" {
+		decision.Category = food.category
+	}
+	sourcePath, err := catalogCategoryPath(currentStore.dataFolder, suggestionStatus(food.allowed), food.category)
+	if err != nil {
+		writeAdminJSON(w, http.StatusConflict, adminSuggestionResponse{Error: err.Error()})
+		return
+	}
+	targetPath, err := catalogCategoryPath(currentStore.dataFolder, status, decision.Category)
+	if err != nil {
+		writeAdminJSON(w, http.StatusBadRequest, adminSuggestionResponse{Error: err.Error()})
+		return
+	}
+	if err := moveCatalogFood(sourcePath, targetPath, food.name); err != nil {
+		writeErrorLog(currentStore.errorLogPath, fmt.Sprintf("dispute accept failed: %v", err))
+		writeAdminJSON(w, http.StatusInternalServerError, adminSuggestionResponse{Error: "catalog write failed"})
+		return
+	}
+
+	decided, err := currentStore.disputes.decide(record.Text, suggestionStateAccepted, status, decision.Category)
+	if err != nil {
+		writeErrorLog(currentStore.errorLogPath, fmt.Sprintf("dispute store write failed: %v", err))
+	}
+	nextStore, err := reloadFoodStore()
+	if err != nil {
+		writeErrorLog(currentStore.errorLogPath, fmt.Sprintf("catalog reload failed: %v", err))
+		writeAdminJSON(w, http.StatusInternalServerError, adminSuggestionResponse{Suggestion: &decided, Error: "catalog reload failed"})
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, adminSuggestionResponse{OK: true, Suggestion: &decided, Foods: len(nextStore.nameFoods)})
+}
+
+// adminRejectDisputeHandler marks a pending dispute as rejected, keeping the
+// catalog status.
+func adminRejectDisputeHandler(w http.ResponseWriter, r *http.Request) {
+	rejectAdminRecord(w, r, getStore().disputes)
+}
+
+func rejectAdminRecord(w http.ResponseWriter, r *http.Request, records *suggestionStore) {
+	_, record, ok := readAdminSuggestionDecision(w, r, records)
+	if !ok {
+		return
+	}
+
+	currentStore := getStore()
+	decided, err := records.decide(record.Text, suggestionStateRejected, "", "")
+	if err != nil {
+		writeErrorLog(currentStore.errorLogPath, fmt.Sprintf("suggestion store write failed: %v", err))
+		writeAdminJSON(w, http.StatusInternalServerError, adminSuggestionResponse{Error: "suggestion store write failed"})
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, adminSuggestionResponse{OK: true, Suggestion: &decided})
+}
+
+// readAdminSuggestionDecision decodes a decision for a pending record in
+// records, writing the error response itself when it cannot be used.
+func readAdminSuggestionDecision(w http.ResponseWriter, r *http.Request, records *suggestionStore) (adminSuggestionDecision, suggestionRecord, bool) {
+	var decision adminSuggestionDecision
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return decision, suggestionRecord{}, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+		http.Error(w, "Error decoding JSON", http.StatusBadRequest)
+		return decision, suggestionRecord{}, false
+	}
+
+	record, err := records.get(decision.Text)
+	if errors.Is(err, errSuggestionNotFound) {
+		writeAdminJSON(w, http.StatusNotFound, adminSuggestionResponse{Error: err.Error()})
+		return decision, record, false
+	}
+	if err != nil {
+		writeErrorLog(getStore().errorLogPath, fmt.Sprintf("suggestion lookup failed: %v", err))
+		writeAdminJSON(w, http.StatusInternalServerError, adminSuggestionResponse{Error: "suggestion store unavailable"})
+		return decision, record, false
+	}
+	if record.State != suggestionStatePending {
+		writeAdminJSON(w, http.StatusConflict, adminSuggestionResponse{Suggestion: &record, Error: "suggestion is already " + record.State})
+		return decision, record, false
+	}
+	return decision, record, true
+}
+
+// catalogCategoryPath resolves an existing category file such as
+// data/allowed/fruits.yaml from a status and file-name category.
+func catalogCategoryPath(dataFolder string, status string, category string) (string, error) {
+	if !catalogCategoryPattern.MatchString(category) {
+		return "", errors.New("category must be a catalog file name such as fruits or herbs_spices")
+	}
+	if strings.TrimSpace(dataFolder) == "" {
+		dataFolder = "data"
+	}
+	folder := "allowed"
+	if status == suggestionStatusNotAllowed {
+		folder = "not_allowed"
+	}
+	catalogPath := filepath.Join(dataFolder, folder, category+".yaml")
+	if _, err := os.Stat(catalogPath); err != nil {
+		return "", fmt.Errorf("unknown %s category %q", status, category)
+	}
+	return catalogPath, nil
+}
+
+// categoryFileName reverses convertPhrase, turning a label such as
+// "Herbs and Spices" back into the herbs_spices file name. Any other run of
+// characters that are not letters or digits also becomes a single
+// underscore, so free-form labels cannot escape the catalog folder.
+func categoryFileName(label string) string {
+	folded := strings.ReplaceAll(foodcatalog.Fold(label), " and ", "_")
+	var name strings.Builder
+	separate := false
+	for _, r := range folded {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			separate = name.Len() > 0
+			continue
+		}
+		if separate {
+			name.WriteByte('_')
+			separate = false
+		}
+		name.WriteRune(r)
+	}
+	return name.String()
+}
+
+// appendCatalogFood writes the entry to the YAML file and, while it exists,
+// its rollback .dat copy.
+func appendCatalogFood(catalogPath string, entry foodcatalog.CatalogEntry) error {
+	catalogWriteLock.Lock()
+	defer catalogWriteLock.Unlock()
+
+	return appendCatalogFoodLocked(catalogPath, entry)
+}
+
+// moveCatalogFood moves the named entry, with its aliases and translations,
+// from one YAML category file to another, and between their rollback .dat
+// copies where they exist.
+func moveCatalogFood(sourcePath string, targetPath string, name string) error {
+	catalogWriteLock.Lock()
+	defer catalogWriteLock.Unlock()
+
+	entry, ok, err := foodcatalog.RemoveEntry(sourcePath, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s is not in %s", name, sourcePath)
+	}
+	if err := appendCatalogFoodLocked(targetPath, entry); err != nil {
+		// Put the food back rather than drop it from the catalog.
+		_ = foodcatalog.AppendEntry(sourcePath, entry)
+		return err
+	}
+	datPath := strings.TrimSuffix(sourcePath, ".yaml") + ".dat"
+	if _, err := os.Stat(datPath); err != nil {
+		return nil
+	}
+	_, _, err = foodcatalog.RemoveEntry(datPath, name)
+	return err
+}
+
+func appendCatalogFoodLocked(catalogPath string, entry foodcatalog.CatalogEntry) error {
+	if err := foodcatalog.AppendEntry(catalogPath, entry); err != nil {
+		return err
+	}
+	datPath := strings.TrimSuffix(catalogPath, ".yaml") + ".dat"
+	if _, err := os.Stat(datPath); err != nil {
+		return nil
+	}
+	return foodcatalog.AppendEntry(datPath, entry)
+}
+
+func writeAdminJSON(w http.ResponseWriter, statusCode int, response any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdminAcceptSuggestionAddsFoodAndReloads(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "fruits.yaml", "- name: Apple\n")
+	writeTestCatalogFile(t, tempDir, "allowed", "fruits.dat", "Apple\n")
+	store = newFoodStore(tempDir)
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.suggestions.record(suggestionVote{Text: "plantain", Allowed: true, ClientIP: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	response := httptest.NewRecorder()
+	adminAcceptSuggestionHandler(response, httptest.NewRequest(http.MethodPost, adminSuggestionsAcceptPath, strings.NewReader(`{"text":"plantain","category":"fruits"}`)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	var result adminSuggestionResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.OK || result.Foods != 2 || result.Suggestion.State != suggestionStateAccepted || result.Suggestion.Category != "fruits" {
+		t.Fatalf("unexpected accept response %#v", result)
+	}
+	yamlData, _ := os.ReadFile(filepath.Join(tempDir, "allowed", "fruits.yaml"))
+	datData, _ := os.ReadFile(filepath.Join(tempDir, "allowed", "fruits.dat"))
+	if string(yamlData) != "- name: Apple\n- name: Plantain\n" || string(datData) != "Apple\nPlantain\n" {
+		t.Fatalf("unexpected catalog files %q %q", yamlData, datData)
+	}
+	if !contains(getStore().match("plantain", "searchbytext", "en").Allowed, "Plantain") {
+		t.Fatal("expected accepted suggestion to be searchable after reload")
+	}
+
+	response = httptest.NewRecorder()
+	adminAcceptSuggestionHandler(response, httptest.NewRequest(http.MethodPost, adminSuggestionsAcceptPath, strings.NewReader(`{"text":"plantain","category":"fruits"}`)))
+	if response.Code != http.StatusConflict {
+		t.Fatalf("expected accepting twice to conflict, got %d", response.Code)
+	}
+}
+
+func TestAdminAcceptSuggestionDefaultsToCategoryHint(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "herbs_spices.yaml", "- name: Basil\n")
+	store = newFoodStore(tempDir)
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.suggestions.record(suggestionVote{Text: "lemongrass", Allowed: true, ClientIP: "192.0.2.1", Category: "Herbs and Spices"}); err != nil {
+		t.Fatal(err)
+	}
+
+	response := httptest.NewRecorder()
+	adminAcceptSuggestionHandler(response, httptest.NewRequest(http.MethodPost, adminSuggestionsAcceptPath, strings.NewReader(`{"text":"lemongrass"}`)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	yamlData, _ := os.ReadFile(filepath.Join(tempDir, "allowed", "herbs_spices.yaml"))
+	if string(yamlData) != "- name: Basil\n- name: Lemongrass\n" {
+		t.Fatalf("unexpected catalog file %q", yamlData)
+	}
+}
+
+func TestAdminAcceptSuggestionRejectsUnknownCategory(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "fruits.yaml", "- name: Apple\n")
+	store = newFoodStore(tempDir)
+	if err := store.suggestions.record(suggestionVote{Text: "plantain", Allowed: true, ClientIP: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{`{"text":"plantain","category":"grains"}`, `{"text":"plantain","category":"../fruits"}`, `{"text":"plantain","category":"fruits","status":"not allowed"}`} {
+		response := httptest.NewRecorder()
+		adminAcceptSuggestionHandler(response, httptest.NewRequest(http.MethodPost, adminSuggestionsAcceptPath, strings.NewReader(body)))
+		if response.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for %s, got %d: %s", body, response.Code, response.Body.String())
+		}
+	}
+}
+
+func TestAdminRejectSuggestionAndListByState(t *testing.T) {
+	store = newFoodStore(t.TempDir())
+	for _, text := range []string{"ghee", "spam spam"} {
+		if err := store.suggestions.record(suggestionVote{Text: text, Allowed: true, ClientIP: "192.0.2.1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	adminRejectSuggestionHandler(response, httptest.NewRequest(http.MethodPost, adminSuggestionsRejectPath, strings.NewReader(`{"text":"spam spam"}`)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+
+	for state, expected := range map[string]string{suggestionStatePending: "ghee", suggestionStateRejected: "spam spam"} {
+		response = httptest.NewRecorder()
+		adminSuggestionsHandler(response, httptest.NewRequest(http.MethodGet, adminSuggestionsPath+"?state="+state, nil))
+		var list adminSuggestionListResponse
+		if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Suggestions) != 1 || list.Suggestions[0].Text != expected {
+			t.Fatalf("unexpected %s suggestions %#v", state, list.Suggestions)
+		}
+	}
+
+	response = httptest.NewRecorder()
+	adminRejectSuggestionHandler(response, httptest.NewRequest(http.MethodPost, adminSuggestionsRejectPath, strings.NewReader(`{"text":"missing"}`)))
+	if response.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", response.Code)
+	}
+}
+
+func TestAdminAcceptDisputeMovesFoodToClaimedStatus(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "oils.yaml", "- name: Ghee\n  aliases:\n    - clarified butter\n- name: Olive Oil\n")
+	writeTestCatalogFile(t, tempDir, "allowed", "oils.dat", "Ghee\tclarified butter\nOlive Oil\n")
+	writeTestCatalogFile(t, tempDir, "not_allowed", "oils.yaml", "- name: Canola Oil\n")
+	store = newFoodStore(tempDir)
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.disputes.record(suggestionVote{Text: "ghee", Allowed: false, ClientIP: "192.0.2.1", Food: "Ghee", CatalogStatus: suggestionStatusAllowed}); err != nil {
+		t.Fatal(err)
+	}
+
+	response := httptest.NewRecorder()
+	adminAcceptDisputeHandler(response, httptest.NewRequest(http.MethodPost, adminDisputesAcceptPath, strings.NewReader(`{"text":"ghee","status":"allowed"}`)))
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("expected keeping the catalog status to be refused, got %d: %s", response.Code, response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	adminAcceptDisputeHandler(response, httptest.NewRequest(http.MethodPost, adminDisputesAcceptPath, strings.NewReader(`{"text":"ghee"}`)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	var result adminSuggestionResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.OK || result.Suggestion.State != suggestionStateAccepted || result.Suggestion.Status != suggestionStatusNotAllowed || result.Suggestion.Category != "oils" {
+		t.Fatalf("unexpected accept response %#v", result)
+	}
+	allowedYAML, _ := os.ReadFile(filepath.Join(tempDir, "allowed", "oils.yaml"))
+	allowedDat, _ := os.ReadFile(filepath.Join(tempDir, "allowed", "oils.dat"))
+	notAllowedYAML, _ := os.ReadFile(filepath.Join(tempDir, "not_allowed", "oils.yaml"))
+	if string(allowedYAML) != "- name: Olive Oil\n" || string(allowedDat) != "Olive Oil\n" ||
+		string(notAllowedYAML) != "- name: Canola Oil\n- name: Ghee\n  aliases:\n    - clarified butter\n" {
+		t.Fatalf("unexpected catalog files %q %q %q", allowedYAML, allowedDat, notAllowedYAML)
+	}
+	if !contains(getStore().match("clarified butter", "searchbytext", "en").NotAllowed, "Ghee") {
+		t.Fatal("expected the moved food to be not allowed after reload")
+	}
+
+	response = httptest.NewRecorder()
+	adminAcceptDisputeHandler(response, httptest.NewRequest(http.MethodPost, adminDisputesAcceptPath, strings.NewReader(`{"text":"ghee"}`)))
+	if response.Code != http.StatusConflict {
+		t.Fatalf("expected accepting twice to conflict, got %d", response.Code)
+	}
+}
+
+func TestAdminRejectDisputeKeepsCatalogStatus(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "oils.yaml", "- name: Ghee\n")
+	store = newFoodStore(tempDir)
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.disputes.record(suggestionVote{Text: "ghee", Allowed: false, ClientIP: "192.0.2.1", Food: "Ghee", CatalogStatus: suggestionStatusAllowed}); err != nil {
+		t.Fatal(err)
+	}
+
+	response := httptest.NewRecorder()
+	adminRejectDisputeHandler(response, httptest.NewRequest(http.MethodPost, adminDisputesRejectPath, strings.NewReader(`{"text":"ghee"}`)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	response = httptest.NewRecorder()
+	adminDisputesHandler(response, httptest.NewRequest(http.MethodGet, adminDisputesPath+"?state=rejected", nil))
+	var list adminDisputeListResponse
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Disputes) != 1 || list.Disputes[0].Food != "Ghee" {
+		t.Fatalf("unexpected rejected disputes %#v", list.Disputes)
+	}
+	yamlData, _ := os.ReadFile(filepath.Join(tempDir, "allowed", "oils.yaml"))
+	if string(yamlData) != "- name: Ghee\n" {
+		t.Fatalf("expected the catalog to stay unchanged, got %q", yamlData)
+	}
+}
+
+func TestAdminSearchOverridesMatchingOptionsPerRequest(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "meats.yaml", "- name: Pork\n")
+	store = newFoodStore(tempDir)
+	store.matchOptions.SoundBonus = 2
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+
+	response := httptest.NewRecorder()
+	adminSearchHandler(response, httptest.NewRequest(http.MethodGet, adminSearchPath+"?key=porc&type=searchbysound", nil))
+	var result adminSearchResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Allowed) != 1 || result.Options.SoundBonus != 2 || result.Options.ShortDistance != 1 {
+		t.Fatalf("expected configured options to match Pork, got %#v", result)
+	}
+
+	response = httptest.NewRecorder()
+	adminSearchHandler(response, httptest.NewRequest(http.MethodGet, adminSearchPath+"?key=porc&type=searchbysound&shortDistance=0", nil))
+	result = adminSearchResponse{}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Allowed) != 0 || result.Options.ShortDistance != 0 || result.Options.SoundBonus != 2 {
+		t.Fatalf("expected the override to drop the spelling match, got %#v", result)
+	}
+
+	for _, query := range []string{"&longDistance=x", "&minTokenLength=0"} {
+		response = httptest.NewRecorder()
+		adminSearchHandler(response, httptest.NewRequest(http.MethodGet, adminSearchPath+"?key=porc"+query, nil))
+		if response.Code != http.StatusBadRequest {
+			t.Fatalf("expected %s to be rejected, got %d", query, response.Code)
+		}
+	}
+}
+
+func TestCategoryFileNameSlugifiesLabels(t *testing.T) {
+	tests := map[string]string{
+		"Herbs and Spices": "herbs_spices",
+		"fruits":           "fruits",
+		" Nuts & Seeds ":   "nuts_seeds",
+		"../Oils/Fats":     "oils_fats",
+		"Lácteos y Quesos": "lacteos_y_quesos",
+	}
+	for label, want := range tests {
+		if got := categoryFileName(label); got != want {
+			t.Errorf("categoryFileName(%q) = %q, want %q", label, got, want)
+		}
+	}
+}
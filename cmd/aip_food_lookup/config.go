@@ -4,6 +4,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
 )
 
 type rateLimitConfig struct {
@@ -14,6 +16,67 @@ type rateLimitConfig struct {
 	WindowSeconds       int
 }
 
+type logRotationConfig struct {
+	Enabled                   bool
+	MaxSizeMB                 int
+	MaxAgeHours               int
+	MaxBackups                int
+	MaxBackupAgeDays          int
+	Compress                  bool
+	FlushIntervalMilliseconds int
+}
+
+type logPrivacyConfig struct {
+	IPMode        string
+	IPHashKey     string
+	DropUserAgent bool
+	SearchKeyMode string
+	SearchLogPath string
+	SearchEvents  bool
+}
+
+type slackOutboxConfig struct {
+	Enabled         bool
+	Path            string
+	RetryMinSeconds int
+	RetryMaxSeconds int
+	MaxAttempts     int
+}
+
+type webhookConfig struct {
+	URL            string
+	Secret         string
+	TimeoutSeconds int
+}
+
+type smtpConfig struct {
+	Host           string
+	Port           int
+	Username       string
+	Password       string
+	From           string
+	To             []string
+	StartTLS       bool
+	TimeoutSeconds int
+}
+
+type sinkChainConfig struct {
+	Feedback        []string
+	FeedbackMode    string
+	Suggestions     []string
+	SuggestionsMode string
+}
+
+type spamFilterConfig struct {
+	Enabled                bool
+	Action                 string
+	HoneypotField          string
+	MaxLinks               int
+	DuplicateWindowSeconds int
+	BlocklistPath          string
+	QuarantinePath         string
+}
+
 type appConfig struct {
 	ListenAddress           string
 	DataFolder              string
@@ -25,12 +88,21 @@ type appConfig struct {
 	GatewaySecret           string
 	SlackFeedbackWebhookURL string
 	FeedbackJSONLPath       string
+	SuggestionsPath         string
 	RequestBodyLimitBytes   int64
 	RateLimit               rateLimitConfig
+	LogRotation             logRotationConfig
+	LogPrivacy              logPrivacyConfig
+	SlackOutbox             slackOutboxConfig
+	Webhook                 webhookConfig
+	SMTP                    smtpConfig
+	Sinks                   sinkChainConfig
+	SpamFilter              spamFilterConfig
+	Matching                foodcatalog.MatchOptions
 }
 
 func loadConfig() appConfig {
-	return appConfig{
+	config := appConfig{
 		ListenAddress:           envString(":8080", "AIP__API__ListenAddress", "AIP_LISTEN_ADDRESS"),
 		DataFolder:              envString("data", "AIP__API__DataFolder", "AIP_DATA_FOLDER"),
 		AccessLogPath:           envString("output/access.log", "AIP__API__AccessLogPath", "AIP_ACCESS_LOG_PATH"),
@@ -41,6 +113,7 @@ func loadConfig() appConfig {
 		GatewaySecret:           envString("", "AIP__API__GatewaySecret", "AIP_GATEWAY_SECRET"),
 		SlackFeedbackWebhookURL: envString("", "AIP__API__SlackFeedbackWebhookUrl", "AIP_SLACK_FEEDBACK_WEBHOOK_URL"),
 		FeedbackJSONLPath:       envString("", "AIP__API__FeedbackJSONLPath", "AIP_FEEDBACK_JSONL_PATH"),
+		SuggestionsPath:         envString("", "AIP__API__SuggestionsPath", "AIP_SUGGESTIONS_PATH"),
 		RequestBodyLimitBytes:   int64(envInt(32768, "AIP__API__RequestBodyLimitBytes", "AIP_REQUEST_BODY_LIMIT_BYTES")),
 		RateLimit: rateLimitConfig{
 			Enabled:             envBool(false, "AIP__API__RateLimit__Enabled", "AIP_RATE_LIMIT_ENABLED"),
@@ -49,7 +122,69 @@ func loadConfig() appConfig {
 			FeedbackPermitLimit: envInt(10, "AIP__API__RateLimit__FeedbackPermitLimit", "AIP_RATE_LIMIT_FEEDBACK_PERMIT_LIMIT"),
 			WindowSeconds:       envInt(60, "AIP__API__RateLimit__WindowSeconds", "AIP_RATE_LIMIT_WINDOW_SECONDS"),
 		},
+		LogRotation: logRotationConfig{
+			Enabled:                   envBool(false, "AIP__API__LogRotation__Enabled", "AIP_LOG_ROTATION_ENABLED"),
+			MaxSizeMB:                 envInt(100, "AIP__API__LogRotation__MaxSizeMB", "AIP_LOG_ROTATION_MAX_SIZE_MB"),
+			MaxAgeHours:               envInt(24, "AIP__API__LogRotation__MaxAgeHours", "AIP_LOG_ROTATION_MAX_AGE_HOURS"),
+			MaxBackups:                envInt(14, "AIP__API__LogRotation__MaxBackups", "AIP_LOG_ROTATION_MAX_BACKUPS"),
+			MaxBackupAgeDays:          envInt(30, "AIP__API__LogRotation__MaxBackupAgeDays", "AIP_LOG_ROTATION_MAX_BACKUP_AGE_DAYS"),
+			Compress:                  envBool(true, "AIP__API__LogRotation__Compress", "AIP_LOG_ROTATION_COMPRESS"),
+			FlushIntervalMilliseconds: envInt(1000, "AIP__API__LogRotation__FlushIntervalMilliseconds", "AIP_LOG_ROTATION_FLUSH_INTERVAL_MILLISECONDS"),
+		},
+		LogPrivacy: logPrivacyConfig{
+			IPMode:        envString(logIPModeFull, "AIP__API__LogPrivacy__IPMode", "AIP_LOG_PRIVACY_IP_MODE"),
+			IPHashKey:     envString("", "AIP__API__LogPrivacy__IPHashKey", "AIP_LOG_PRIVACY_IP_HASH_KEY"),
+			DropUserAgent: envBool(false, "AIP__API__LogPrivacy__DropUserAgent", "AIP_LOG_PRIVACY_DROP_USER_AGENT"),
+			SearchKeyMode: envString(searchKeyLogInline, "AIP__API__LogPrivacy__SearchKeyMode", "AIP_LOG_PRIVACY_SEARCH_KEY_MODE"),
+			SearchLogPath: envString("output/search.log", "AIP__API__LogPrivacy__SearchLogPath", "AIP_LOG_PRIVACY_SEARCH_LOG_PATH"),
+			SearchEvents:  envBool(true, "AIP__API__LogPrivacy__SearchEvents", "AIP_LOG_PRIVACY_SEARCH_EVENTS"),
+		},
+		SlackOutbox: slackOutboxConfig{
+			Enabled:         envBool(true, "AIP__API__SlackOutbox__Enabled", "AIP_SLACK_OUTBOX_ENABLED"),
+			Path:            envString("", "AIP__API__SlackOutbox__Path", "AIP_SLACK_OUTBOX_PATH"),
+			RetryMinSeconds: envInt(5, "AIP__API__SlackOutbox__RetryMinSeconds", "AIP_SLACK_OUTBOX_RETRY_MIN_SECONDS"),
+			RetryMaxSeconds: envInt(3600, "AIP__API__SlackOutbox__RetryMaxSeconds", "AIP_SLACK_OUTBOX_RETRY_MAX_SECONDS"),
+			MaxAttempts:     envInt(48, "AIP__API__SlackOutbox__MaxAttempts", "AIP_SLACK_OUTBOX_MAX_ATTEMPTS"),
+		},
+		Webhook: webhookConfig{
+			URL:            envString("", "AIP__API__Webhook__Url", "AIP_WEBHOOK_URL"),
+			Secret:         envString("", "AIP__API__Webhook__Secret", "AIP_WEBHOOK_SECRET"),
+			TimeoutSeconds: envInt(5, "AIP__API__Webhook__TimeoutSeconds", "AIP_WEBHOOK_TIMEOUT_SECONDS"),
+		},
+		SMTP: smtpConfig{
+			Host:           envString("", "AIP__API__Smtp__Host", "AIP_SMTP_HOST"),
+			Port:           envInt(587, "AIP__API__Smtp__Port", "AIP_SMTP_PORT"),
+			Username:       envString("", "AIP__API__Smtp__Username", "AIP_SMTP_USERNAME"),
+			Password:       envString("", "AIP__API__Smtp__Password", "AIP_SMTP_PASSWORD"),
+			From:           envString("", "AIP__API__Smtp__From", "AIP_SMTP_FROM"),
+			To:             envList("AIP__API__Smtp__To", "AIP_SMTP_TO"),
+			StartTLS:       envBool(true, "AIP__API__Smtp__StartTls", "AIP_SMTP_STARTTLS"),
+			TimeoutSeconds: envInt(10, "AIP__API__Smtp__TimeoutSeconds", "AIP_SMTP_TIMEOUT_SECONDS"),
+		},
+		Sinks: sinkChainConfig{
+			Feedback:        envList("AIP__API__Sinks__Feedback", "AIP_SINKS_FEEDBACK"),
+			FeedbackMode:    envString("", "AIP__API__Sinks__FeedbackMode", "AIP_SINKS_FEEDBACK_MODE"),
+			Suggestions:     envList("AIP__API__Sinks__Suggestions", "AIP_SINKS_SUGGESTIONS"),
+			SuggestionsMode: envString("", "AIP__API__Sinks__SuggestionsMode", "AIP_SINKS_SUGGESTIONS_MODE"),
+		},
+		SpamFilter: spamFilterConfig{
+			Enabled:                envBool(false, "AIP__API__SpamFilter__Enabled", "AIP_SPAM_FILTER_ENABLED"),
+			Action:                 envString(spamActionQuarantine, "AIP__API__SpamFilter__Action", "AIP_SPAM_FILTER_ACTION"),
+			HoneypotField:          envString("website", "AIP__API__SpamFilter__HoneypotField", "AIP_SPAM_FILTER_HONEYPOT_FIELD"),
+			MaxLinks:               envInt(2, "AIP__API__SpamFilter__MaxLinks", "AIP_SPAM_FILTER_MAX_LINKS"),
+			DuplicateWindowSeconds: envInt(600, "AIP__API__SpamFilter__DuplicateWindowSeconds", "AIP_SPAM_FILTER_DUPLICATE_WINDOW_SECONDS"),
+			BlocklistPath:          envString("", "AIP__API__SpamFilter__BlocklistPath", "AIP_SPAM_FILTER_BLOCKLIST_PATH"),
+			QuarantinePath:         envString("", "AIP__API__SpamFilter__QuarantinePath", "AIP_SPAM_FILTER_QUARANTINE_PATH"),
+		},
+		Matching: foodcatalog.MatchOptions{
+			ShortQueryLength: envInt(4, "AIP__API__Matching__ShortQueryLength", "AIP_MATCHING_SHORT_QUERY_LENGTH"),
+			ShortDistance:    envInt(1, "AIP__API__Matching__ShortDistance", "AIP_MATCHING_SHORT_DISTANCE"),
+			LongDistance:     envInt(3, "AIP__API__Matching__LongDistance", "AIP_MATCHING_LONG_DISTANCE"),
+			SoundBonus:       envInt(1, "AIP__API__Matching__SoundBonus", "AIP_MATCHING_SOUND_BONUS"),
+			MinTokenLength:   envInt(3, "AIP__API__Matching__MinTokenLength", "AIP_MATCHING_MIN_TOKEN_LENGTH"),
+		},
 	}
+	return config
 }
 
 func envString(defaultValue string, names ...string) string {
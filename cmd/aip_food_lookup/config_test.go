@@ -45,3 +45,30 @@ func TestLoadConfigReadsNestedYamlEnvironment(t *testing.T) {
 		t.Fatalf("unexpected rate limit config: %#v", config.RateLimit)
 	}
 }
+
+func TestLoadConfigReadsMatchingOptions(t *testing.T) {
+	t.Setenv("AIP__API__Matching__LongDistance", "2")
+	t.Setenv("AIP_MATCHING_MIN_TOKEN_LENGTH", "4")
+
+	config := loadConfig()
+
+	if config.Matching.LongDistance != 2 || config.Matching.MinTokenLength != 4 || config.Matching.ShortDistance != 1 || config.Matching.ShortQueryLength != 4 || config.Matching.SoundBonus != 1 {
+		t.Fatalf("unexpected matching options: %#v", config.Matching)
+	}
+}
+
+func TestLoadConfigEnablesSearchEventsByDefault(t *testing.T) {
+	if !loadConfig().LogPrivacy.SearchEvents {
+		t.Fatal("expected search events on in inline mode")
+	}
+
+	t.Setenv("AIP__API__LogPrivacy__SearchKeyMode", "separate")
+	if !loadConfig().LogPrivacy.SearchEvents {
+		t.Fatal("expected search events on in separate mode")
+	}
+
+	t.Setenv("AIP__API__LogPrivacy__SearchEvents", "false")
+	if loadConfig().LogPrivacy.SearchEvents {
+		t.Fatal("expected SearchEvents to turn search events off")
+	}
+}
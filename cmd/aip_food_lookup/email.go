@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpFeedbackSink emails feedback to the maintainers with Reply-To set to
+// the user's address.
+type smtpFeedbackSink struct {
+	host      string
+	port      int
+	username  string
+	password  string
+	from      string
+	to        []string
+	startTLS  bool
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	now       func() time.Time
+}
+
+// newSMTPFeedbackSink returns nil unless a host, sender and recipient are
+// configured.
+func newSMTPFeedbackSink(config appConfig) *smtpFeedbackSink {
+	settings := config.SMTP
+	if strings.TrimSpace(settings.Host) == "" || strings.TrimSpace(settings.From) == "" || len(settings.To) == 0 {
+		return nil
+	}
+	return &smtpFeedbackSink{
+		host:      settings.Host,
+		port:      settings.Port,
+		username:  settings.Username,
+		password:  settings.Password,
+		from:      settings.From,
+		to:        settings.To,
+		startTLS:  settings.StartTLS,
+		timeout:   time.Duration(settings.TimeoutSeconds) * time.Second,
+		tlsConfig: &tls.Config{ServerName: settings.Host},
+		now:       time.Now,
+	}
+}
+
+// submitFeedback sends one message. With startTLS set the connection must
+// upgrade before any credentials or feedback are sent.
+func (s *smtpFeedbackSink) submitFeedback(request feedbackRequest) error {
+	message, err := s.buildMessage(request)
+	if err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	conn, err := net.DialTimeout("tcp", address, s.timeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if s.startTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(s.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(envelopeAddress(s.from)); err != nil {
+		return err
+	}
+	for _, recipient := range s.to {
+		if err := client.Rcpt(envelopeAddress(recipient)); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage renders a quoted-printable text message. User values only
+// reach headers through encoders that cannot emit line breaks, and Reply-To
+// is only set for an address that parses.
+func (s *smtpFeedbackSink) buildMessage(request feedbackRequest) ([]byte, error) {
+	subject := strings.TrimSpace(request.Subject)
+	if subject == "" {
+		subject = "(no subject)"
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", s.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(s.to, ", "))
+	if replyTo, err := mail.ParseAddress(strings.TrimSpace(request.Email)); err == nil {
+		replyTo.Name = strings.TrimSpace(request.Name)
+		fmt.Fprintf(&message, "Reply-To: %s\r\n", replyTo.String())
+	}
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "AIP Food Lookup feedback: "+singleLine(subject)))
+	fmt.Fprintf(&message, "Date: %s\r\n", s.now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&message)
+	fmt.Fprintf(body, "Name: %s\r\nEmail: %s\r\nSource: %s\r\nClient: %s\r\nID: %s\r\n\r\n%s\r\n",
+		orNone(request.Name), orNone(request.Email), orNone(request.Source), orNone(request.Client+" "+request.AppVersion),
+		orNone(request.ID), strings.TrimSpace(request.Message))
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
+
+// envelopeAddress strips a display name such as "AIP <noreply@example.com>"
+// for the SMTP envelope, which only takes the bare address.
+func envelopeAddress(value string) string {
+	if address, err := mail.ParseAddress(value); err == nil {
+		return address.Address
+	}
+	return strings.TrimSpace(value)
+}
+
+func singleLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func orNone(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "none"
+	}
+	return value
+}
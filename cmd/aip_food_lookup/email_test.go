@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer accepts one session at a time and records what it received.
+type fakeSMTPServer struct {
+	listener net.Listener
+	tls      *tls.Config
+	sessions chan fakeSMTPSession
+}
+
+type fakeSMTPSession struct {
+	TLS   bool
+	Auth  string
+	From  string
+	To    []string
+	Data  string
+	Error string
+}
+
+func startFakeSMTPServer(t *testing.T, tlsConfig *tls.Config) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTPServer{listener: listener, tls: tlsConfig, sessions: make(chan fakeSMTPSession, 4)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.sessions <- server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) fakeSMTPSession {
+	defer func() { conn.Close() }()
+	var session fakeSMTPSession
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return session
+		}
+		command := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			if s.tls != nil && !session.TLS {
+				reply("250-localhost\r\n250 STARTTLS")
+			} else {
+				reply("250-localhost\r\n250 AUTH PLAIN")
+			}
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				session.Error = err.Error()
+				return session
+			}
+			conn, reader, session.TLS = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			session.Auth = command
+			reply("235 2.7.0 accepted")
+		case "MAIL":
+			session.From = command
+			reply("250 ok")
+		case "RCPT":
+			session.To = append(session.To, command)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil || dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			session.Data = data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return session
+		default:
+			reply("502 unknown " + verb)
+		}
+	}
+}
+
+func TestSMTPFeedbackSinkSendsOverStartTLSWithReplyTo(t *testing.T) {
+	certificateServer := httptest.NewTLSServer(nil)
+	defer certificateServer.Close()
+	smtpServer := startFakeSMTPServer(t, &tls.Config{Certificates: certificateServer.TLS.Certificates})
+
+	config := appConfig{SMTP: smtpConfig{
+		Host:           "127.0.0.1",
+		Port:           smtpServer.port(),
+		Username:       "mailer",
+		Password:       "hunter2",
+		From:           "AIP Food Lookup <noreply@example.com>",
+		To:             []string{"team@example.com", "Joe <joe@example.org>"},
+		StartTLS:       true,
+		TimeoutSeconds: 5,
+	}}
+	sink := newSMTPFeedbackSink(config)
+	roots := x509.NewCertPool()
+	roots.AddCert(certificateServer.Certificate())
+	sink.tlsConfig = &tls.Config{ServerName: "127.0.0.1", RootCAs: roots}
+
+	err := sink.submitFeedback(feedbackRequest{
+		Name:    "Jo Smith",
+		Email:   "jo@example.net",
+		Subject: "Broken\r\nBcc: everyone@example.com",
+		Message: "Ghee shows twice.\n.\nThanks",
+		Source:  "ios",
+	})
+	if err != nil {
+		t.Fatalf("submitFeedback: %v", err)
+	}
+
+	session := <-smtpServer.sessions
+	if !session.TLS {
+		t.Fatal("expected the session to upgrade with STARTTLS before sending")
+	}
+	credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(session.Auth, "AUTH PLAIN "))
+	if string(credentials) != "\x00mailer\x00hunter2" {
+		t.Fatalf("unexpected AUTH %q", session.Auth)
+	}
+	if !strings.HasPrefix(session.From, "MAIL FROM:<noreply@example.com>") {
+		t.Fatalf("unexpected MAIL %q", session.From)
+	}
+	if len(session.To) != 2 || session.To[1] != "RCPT TO:<joe@example.org>" {
+		t.Fatalf("unexpected RCPT %#v", session.To)
+	}
+	for _, expected := range []string{
+		"Reply-To: \"Jo Smith\" <jo@example.net>\r\n",
+		"Subject: AIP Food Lookup feedback: Broken Bcc: everyone@example.com\r\n",
+		"Source: ios",
+		"\r\n..\r\nThanks",
+	} {
+		if !strings.Contains(session.Data, expected) {
+			t.Fatalf("expected %q in message %q", expected, session.Data)
+		}
+	}
+	if strings.Contains(session.Data, "\r\nBcc:") {
+		t.Fatalf("subject injected a header: %q", session.Data)
+	}
+}
+
+func TestSMTPFeedbackSinkFallsBackWithoutStartTLS(t *testing.T) {
+	smtpServer := startFakeSMTPServer(t, nil)
+	tempDir := t.TempDir()
+	config := appConfig{
+		DataFolder:   tempDir,
+		ErrorLogPath: filepath.Join(tempDir, "errors.log"),
+		SMTP: smtpConfig{
+			Host:           "127.0.0.1",
+			Port:           smtpServer.port(),
+			Username:       "mailer",
+			Password:       "hunter2",
+			From:           "noreply@example.com",
+			To:             []string{"team@example.com"},
+			StartTLS:       true,
+			TimeoutSeconds: 5,
+		},
+	}
+
+	store = newFoodStore(tempDir)
+	store.feedbackSink = newFeedbackSink(config, nil)
+	if err := store.submitFeedback(feedbackRequest{Message: "Works?"}); err != nil {
+		t.Fatalf("expected the stored feedback to hide the SMTP error, got %v", err)
+	}
+	select {
+	case session := <-smtpServer.sessions:
+		if session.Auth != "" || session.Data != "" {
+			t.Fatalf("credentials or feedback sent without TLS: %#v", session)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an SMTP session")
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "feedback.jsonl"))
+	if err != nil || !strings.Contains(string(content), `"message":"Works?"`) {
+		t.Fatalf("expected fallback feedback file, got %q err %v", content, err)
+	}
+	errorLog, _ := os.ReadFile(filepath.Join(tempDir, "errors.log"))
+	if !strings.Contains(string(errorLog), "smtp feedback failed: smtp server does not support STARTTLS") {
+		t.Fatalf("expected SMTP failure log, got %q", errorLog)
+	}
+}
+
+func TestNewSMTPFeedbackSinkRequiresRecipients(t *testing.T) {
+	if newSMTPFeedbackSink(appConfig{SMTP: smtpConfig{Host: "mail.example.com", Port: 587, From: "a@example.com"}}) != nil {
+		t.Fatal("expected no SMTP sink without recipients")
+	}
+	if loadConfig().SMTP.Port != 587 {
+		t.Fatal("expected the submission port by default")
+	}
+}
@@ -1,88 +1,64 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 )
 
-type slackFeedbackSink struct {
+// slackSink posts feedback and suggestions to a Slack incoming webhook,
+// through outbox when it is not nil.
+type slackSink struct {
 	webhookURL string
 	client     *http.Client
-	fallback   feedbackSink
-	errorLog   string
+	outbox     *notificationOutbox
 }
 
-func newFeedbackSink(config appConfig) feedbackSink {
-	fallback := fileFeedbackSink{
-		dataFolder: config.DataFolder,
-		filePath:   config.FeedbackJSONLPath,
-	}
-
+// newSlackSink returns nil when no Slack webhook is configured.
+func newSlackSink(config appConfig, outbox *notificationOutbox) *slackSink {
 	if strings.TrimSpace(config.SlackFeedbackWebhookURL) == "" {
-		return fallback
+		return nil
 	}
 
-	return slackFeedbackSink{
+	return &slackSink{
 		webhookURL: config.SlackFeedbackWebhookURL,
 		client: &http.Client{
 			Timeout: 5 * time.Second,
 		},
-		fallback: fallback,
-		errorLog: config.ErrorLogPath,
+		outbox: outbox,
 	}
 }
 
-func (s slackFeedbackSink) submitFeedback(request feedbackRequest) error {
-	if err := s.submitSlack(request); err == nil {
-		return nil
-	} else {
-		writeErrorLog(s.errorLog, fmt.Sprintf("slack feedback failed: %v", err))
-		if fallbackErr := s.fallback.submitFeedback(request); fallbackErr != nil {
-			return errors.Join(err, fallbackErr)
-		}
-		return nil
-	}
+// newFeedbackSink builds the feedback sink chain from config.
+func newFeedbackSink(config appConfig, outbox *notificationOutbox) feedbackSink {
+	return newSinkChain(config, outbox, sinkChainFeedback)
 }
 
-func (s slackFeedbackSink) submitSlack(request feedbackRequest) error {
-	payload, err := json.Marshal(map[string]any{
-		"text":   buildFeedbackSlackMessage(request),
-		"mrkdwn": true,
-	})
-	if err != nil {
-		return err
-	}
-
-	httpRequest, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(payload))
-	if err != nil {
-		return err
-	}
-	httpRequest.Header.Set("Content-Type", "application/json")
+func (s *slackSink) submitFeedback(request feedbackRequest) error {
+	return s.post("slack feedback", buildFeedbackSlackMessage(request))
+}
 
-	response, err := s.client.Do(httpRequest)
+func (s *slackSink) post(kind string, text string) error {
+	payload, err := slackPayload(text)
 	if err != nil {
 		return err
 	}
-	defer response.Body.Close()
-
-	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return fmt.Errorf("slack returned status %d", response.StatusCode)
+	if s.outbox != nil {
+		return s.outbox.enqueue(kind, payload)
 	}
-	return nil
+	return postSlack(s.client, s.webhookURL, payload)
 }
 
 func buildFeedbackSlackMessage(request feedbackRequest) string {
 	return fmt.Sprintf(
-		"*AIP Food Lookup feedback*\n*Name:* %s\n*Email:* %s\n*Subject:* %s\n*Source:* %s\n*Message:*\n%s",
+		"*AIP Food Lookup feedback*\n*Name:* %s\n*Email:* %s\n*Subject:* %s\n*Source:* %s\n*Client:* %s\n*ID:* %s\n*Message:*\n%s",
 		escapeSlackValue(request.Name),
 		escapeSlackValue(request.Email),
 		escapeSlackValue(request.Subject),
 		escapeSlackValue(request.Source),
+		escapeSlackValue(request.Client+" "+request.AppVersion),
+		escapeSlackValue(request.ID),
 		escapeSlackValue(request.Message),
 	)
 }
@@ -16,13 +16,14 @@ func TestSlackFeedbackSinkFallsBackToJsonlWhenSlackFails(t *testing.T) {
 	defer slackServer.Close()
 
 	tempDir := t.TempDir()
-	sink := newFeedbackSink(appConfig{
+	store = newFoodStore(tempDir)
+	store.feedbackSink = newFeedbackSink(appConfig{
 		DataFolder:              tempDir,
 		ErrorLogPath:            filepath.Join(tempDir, "errors.log"),
 		SlackFeedbackWebhookURL: slackServer.URL,
-	})
+	}, nil)
 
-	err := sink.submitFeedback(feedbackRequest{
+	err := store.submitFeedback(feedbackRequest{
 		Name:    "Joe",
 		Email:   "joe@example.com",
 		Subject: "Hello",
@@ -30,7 +31,7 @@ func TestSlackFeedbackSinkFallsBackToJsonlWhenSlackFails(t *testing.T) {
 		Source:  "android",
 	})
 	if err != nil {
-		t.Fatalf("expected JSONL fallback to hide Slack error, got %v", err)
+		t.Fatalf("expected the stored feedback to hide the Slack error, got %v", err)
 	}
 
 	content, err := os.ReadFile(filepath.Join(tempDir, "feedback.jsonl"))
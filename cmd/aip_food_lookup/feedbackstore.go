@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	feedbackStatusAll     = "all"
+	feedbackStatusNew     = "new"
+	feedbackStatusHandled = "handled"
+
+	feedbackFileName = "feedback.jsonl"
+)
+
+var (
+	errFeedbackNotFound = errors.New("feedback not found")
+
+	// feedbackFileLock serializes appends with the rewrites that record
+	// status changes, which may come from different foodStore generations.
+	feedbackFileLock sync.Mutex
+)
+
+// feedbackRecord is one line of feedback.jsonl. Lines written before
+// feedback had IDs get a stable ID derived from their content, and are
+// rewritten with it the first time any status changes.
+type feedbackRecord struct {
+	feedbackRequest
+	Status      string     `json:"status,omitempty"`
+	HandledAt   *time.Time `json:"handledAt,omitempty"`
+	HandledNote string     `json:"handledNote,omitempty"`
+}
+
+// feedbackFilter selects feedback for the admin list. Empty fields match
+// everything; Query matches name, email, subject or message ignoring case.
+type feedbackFilter struct {
+	Status     string
+	Source     string
+	Client     string
+	AppVersion string
+	Query      string
+	Since      time.Time
+	Limit      int
+}
+
+func newFeedbackID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// feedbackFilePath places feedback beside the catalog unless configured.
+func feedbackFilePath(dataFolder string, configured string) string {
+	if strings.TrimSpace(configured) != "" {
+		return configured
+	}
+	return filepath.Join(dataFolder, feedbackFileName)
+}
+
+func appendFeedbackRecord(filePath string, record feedbackRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	feedbackFileLock.Lock()
+	defer feedbackFileLock.Unlock()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(payload, '\n'))
+	return err
+}
+
+// listFeedback returns matching feedback, newest first.
+func listFeedback(filePath string, filter feedbackFilter) ([]feedbackRecord, error) {
+	feedbackFileLock.Lock()
+	records, err := readFeedbackRecords(filePath)
+	feedbackFileLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+	result := []feedbackRecord{}
+	for index := len(records) - 1; index >= 0; index-- {
+		record := records[index]
+		if filter.Status != "" && filter.Status != feedbackStatusAll && record.Status != filter.Status {
+			continue
+		}
+		if (filter.Source != "" && !strings.EqualFold(record.Source, filter.Source)) ||
+			(filter.Client != "" && !strings.EqualFold(record.Client, filter.Client)) ||
+			(filter.AppVersion != "" && record.AppVersion != filter.AppVersion) {
+			continue
+		}
+		if !filter.Since.IsZero() && record.ReceivedAt.Before(filter.Since) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(strings.Join([]string{record.Name, record.Email, record.Subject, record.Message}, "\n")), query) {
+			continue
+		}
+		result = append(result, record)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ReceivedAt.After(result[j].ReceivedAt)
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+// markFeedback sets a record handled, or back to new, and rewrites the file.
+// Lines that do not parse as feedback are written back unchanged.
+func markFeedback(filePath string, id string, handled bool, note string, now time.Time) (feedbackRecord, error) {
+	feedbackFileLock.Lock()
+	defer feedbackFileLock.Unlock()
+	lines, err := readFeedbackLines(filePath)
+	if err != nil {
+		return feedbackRecord{}, err
+	}
+
+	for index := range lines {
+		record := &lines[index].record
+		if lines[index].raw != nil || record.ID != id {
+			continue
+		}
+		if handled {
+			handledAt := now.UTC()
+			record.Status, record.HandledAt, record.HandledNote = feedbackStatusHandled, &handledAt, note
+		} else {
+			record.Status, record.HandledAt, record.HandledNote = feedbackStatusNew, nil, ""
+		}
+		return *record, writeFeedbackLines(filePath, lines)
+	}
+	return feedbackRecord{}, errFeedbackNotFound
+}
+
+// feedbackLine is one line of feedback.jsonl. raw holds the bytes of a line
+// that did not parse, such as a truncated write, so rewrites keep it as is.
+type feedbackLine struct {
+	record feedbackRecord
+	raw    []byte
+}
+
+func readFeedbackRecords(filePath string) ([]feedbackRecord, error) {
+	lines, err := readFeedbackLines(filePath)
+	var records []feedbackRecord
+	for _, line := range lines {
+		if line.raw == nil {
+			records = append(records, line.record)
+		}
+	}
+	return records, err
+}
+
+func readFeedbackLines(filePath string) ([]feedbackLine, error) {
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []feedbackLine
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record feedbackRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			lines = append(lines, feedbackLine{raw: bytes.Clone(scanner.Bytes())})
+			continue
+		}
+		if record.ID == "" {
+			// The line number keeps identical legacy lines apart.
+			sum := sha256.Sum256(append([]byte(strconv.Itoa(number)+"\x00"), scanner.Bytes()...))
+			record.ID = "legacy-" + hex.EncodeToString(sum[:6])
+		}
+		if record.Status == "" {
+			record.Status = feedbackStatusNew
+		}
+		lines = append(lines, feedbackLine{record: record})
+	}
+	return lines, scanner.Err()
+}
+
+func writeFeedbackLines(filePath string, lines []feedbackLine) error {
+	var buffer bytes.Buffer
+	for _, line := range lines {
+		payload := line.raw
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(line.record); err != nil {
+				return err
+			}
+		}
+		buffer.Write(append(payload, '\n'))
+	}
+	temporary := filePath + ".tmp"
+	if err := os.WriteFile(temporary, buffer.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, filePath)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedbackHandlerRecordsIDTimeAndClientHeaders(t *testing.T) {
+	tempDir := t.TempDir()
+	store = newFoodStore(tempDir)
+
+	request := httptest.NewRequest(http.MethodPost, "/feedback", strings.NewReader(`{"name":"Jo","message":"Love it","id":"forged"}`))
+	request.Header.Set("X-AIP-App-Version", " 2.4.1 ")
+	request.Header.Set("X-AIP-Client", "ios")
+	response := httptest.NewRecorder()
+	before := time.Now().UTC()
+	feedbackHandler(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", response.Code, response.Body.String())
+	}
+
+	records, err := readFeedbackRecords(filepath.Join(tempDir, feedbackFileName))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("records = %#v, err %v", records, err)
+	}
+	record := records[0]
+	if len(record.ID) != 16 || record.ID == "forged" || record.ReceivedAt.Before(before.Add(-time.Second)) {
+		t.Fatalf("expected a server ID and received time, got %#v", record)
+	}
+	if record.AppVersion != "2.4.1" || record.Client != "ios" || record.Status != feedbackStatusNew {
+		t.Fatalf("unexpected client metadata %#v", record)
+	}
+}
+
+func TestAdminFeedbackListFiltersAndMarksHandled(t *testing.T) {
+	tempDir := t.TempDir()
+	store = newFoodStore(tempDir)
+	filePath := filepath.Join(tempDir, feedbackFileName)
+	legacy := `{"name":"Old","email":"","subject":"App feedback","message":"Before IDs","source":"android"}` + "\n"
+	if err := os.WriteFile(filePath, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, request := range []feedbackRequest{
+		{ID: "a1", ReceivedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Name: "Ann", Message: "Ghee is missing", Source: "ios", Client: "ios", AppVersion: "2.4.1"},
+		{ID: "b2", ReceivedAt: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Name: "Ben", Message: "Crash on search", Source: "android", Client: "android", AppVersion: "2.4.0"},
+	} {
+		if err := store.submitFeedback(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func(query string) []feedbackRecord {
+		t.Helper()
+		response := httptest.NewRecorder()
+		adminFeedbackHandler(response, httptest.NewRequest(http.MethodGet, adminFeedbackPath+query, nil))
+		if response.Code != http.StatusOK {
+			t.Fatalf("list %s: status %d: %s", query, response.Code, response.Body.String())
+		}
+		var payload adminFeedbackListResponse
+		if err := json.NewDecoder(response.Body).Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		return payload.Feedback
+	}
+
+	all := list("")
+	if len(all) != 3 || all[0].ID != "b2" || all[1].ID != "a1" || !strings.HasPrefix(all[2].ID, "legacy-") {
+		t.Fatalf("expected newest first with legacy last, got %#v", all)
+	}
+	if filtered := list("?client=IOS&q=ghee"); len(filtered) != 1 || filtered[0].ID != "a1" {
+		t.Fatalf("client and query filter = %#v", filtered)
+	}
+	if filtered := list("?since=2026-03-02T00:00:00Z&limit=5"); len(filtered) != 1 || filtered[0].ID != "b2" {
+		t.Fatalf("since filter = %#v", filtered)
+	}
+
+	for _, id := range []string{"a1", all[2].ID} {
+		response := httptest.NewRecorder()
+		adminHandleFeedbackHandler(response, httptest.NewRequest(http.MethodPost, adminFeedbackHandlePath, strings.NewReader(`{"id":"`+id+`","note":"replied"}`)))
+		if response.Code != http.StatusOK {
+			t.Fatalf("handle %s: status %d: %s", id, response.Code, response.Body.String())
+		}
+	}
+	if pending := list(""); len(pending) != 1 || pending[0].ID != "b2" {
+		t.Fatalf("expected only b2 pending, got %#v", pending)
+	}
+	handled := list("?status=handled")
+	if len(handled) != 2 || handled[0].HandledNote != "replied" || handled[0].HandledAt == nil {
+		t.Fatalf("handled = %#v", handled)
+	}
+
+	response := httptest.NewRecorder()
+	adminHandleFeedbackHandler(response, httptest.NewRequest(http.MethodPost, adminFeedbackHandlePath, strings.NewReader(`{"id":"a1","handled":false}`)))
+	if response.Code != http.StatusOK || len(list("")) != 2 {
+		t.Fatalf("expected a1 reopened, status %d", response.Code)
+	}
+	response = httptest.NewRecorder()
+	adminHandleFeedbackHandler(response, httptest.NewRequest(http.MethodPost, adminFeedbackHandlePath, strings.NewReader(`{"id":"nope"}`)))
+	if response.Code != http.StatusNotFound {
+		t.Fatalf("unknown id status %d", response.Code)
+	}
+	response = httptest.NewRecorder()
+	adminFeedbackHandler(response, httptest.NewRequest(http.MethodGet, adminFeedbackPath+"?status=done", nil))
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("unknown status filter returned %d", response.Code)
+	}
+
+	content, _ := os.ReadFile(filePath)
+	if strings.Count(string(content), "\n") != 3 || !strings.Contains(string(content), all[2].ID) {
+		t.Fatalf("expected the legacy line rewritten with its ID, got %q", content)
+	}
+}
+
+func TestMarkFeedbackKeepsUnparseableLinesAndSeparatesIdenticalLegacyLines(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), feedbackFileName)
+	legacy := `{"name":"Old","message":"Same text"}`
+	truncated := `{"id":"c3","message":"cut off mid-wri`
+	content := legacy + "\n" + truncated + "\n" + legacy + "\n"
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := readFeedbackRecords(filePath)
+	if err != nil || len(records) != 2 || records[0].ID == records[1].ID {
+		t.Fatalf("expected two legacy records with distinct IDs, got %#v err %v", records, err)
+	}
+	if _, err := markFeedback(filePath, records[1].ID, true, "", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	rewritten, _ := os.ReadFile(filePath)
+	lines := strings.Split(strings.TrimSuffix(string(rewritten), "\n"), "\n")
+	if len(lines) != 3 || lines[1] != truncated {
+		t.Fatalf("expected the truncated line kept in place, got %q", rewritten)
+	}
+	after, err := readFeedbackRecords(filePath)
+	if err != nil || len(after) != 2 || after[0].ID != records[0].ID || after[0].Status != feedbackStatusNew || after[1].Status != feedbackStatusHandled {
+		t.Fatalf("expected only the second legacy record handled, got %#v err %v", after, err)
+	}
+}
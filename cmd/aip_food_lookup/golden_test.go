@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+// TestSearchHandlerMatchesGoldenQueries replays the foodcatalog golden
+// queries through /search against the real catalog, so the API's own loading
+// of the catalog cannot drift from what the golden file pins.
+func TestSearchHandlerMatchesGoldenQueries(t *testing.T) {
+	store = newFoodStore("../../data")
+	if err := store.processDirectory("../../data"); err != nil {
+		t.Fatal(err)
+	}
+	queries, err := foodcatalog.LoadGoldenQueries(filepath.Join("..", "..", "internal", "foodcatalog", "testdata", "golden_queries.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range queries {
+		query := query
+		t.Run(strings.Join(strings.Fields(query.Type+" "+query.Lang+" "+query.Query), " "), func(t *testing.T) {
+			parameters := url.Values{"key": {query.Query}, "type": {query.Type}}
+			if query.Lang != "" {
+				parameters.Set("lang", query.Lang)
+			}
+			response := httptest.NewRecorder()
+			searchHandler(response, httptest.NewRequest(http.MethodGet, "/search?"+parameters.Encode(), nil))
+			var result responseData
+			if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Join(result.Allowed, "\n") != strings.Join(query.Allowed, "\n") {
+				t.Errorf("allowed = %q, want %q", result.Allowed, query.Allowed)
+			}
+			if strings.Join(result.NotAllowed, "\n") != strings.Join(query.NotAllowed, "\n") {
+				t.Errorf("not_allowed = %q, want %q", result.NotAllowed, query.NotAllowed)
+			}
+			for _, name := range query.MustNotInclude {
+				if contains(result.Allowed, name) || contains(result.NotAllowed, name) {
+					t.Errorf("returned %q, which must not be included", name)
+				}
+			}
+		})
+	}
+}
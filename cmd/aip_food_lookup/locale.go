@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+	"golang.org/x/text/language"
+)
+
+// requestLanguage picks the response language: the lang query parameter when
+// given, otherwise the most preferred Accept-Language entry the catalog has
+// translations for. Either falls back to English, so Content-Language never
+// names a language the catalog has no translations for.
+func (s *foodStore) requestLanguage(r *http.Request) string {
+	if lang := foodcatalog.Language(r.URL.Query().Get("lang")); lang != "" {
+		if lang == foodcatalog.DefaultLanguage || s.languages[lang] {
+			return lang
+		}
+		return foodcatalog.DefaultLanguage
+	}
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil {
+		return foodcatalog.DefaultLanguage
+	}
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		if lang := base.String(); lang == foodcatalog.DefaultLanguage || s.languages[lang] {
+			return lang
+		}
+	}
+	return foodcatalog.DefaultLanguage
+}
+
+// setLanguageHeaders tells clients and caches which language a localized
+// response is in.
+func setLanguageHeaders(w http.ResponseWriter, lang string) {
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+}
+
+// categoryLabels returns the /categories labels for one status in lang,
+// falling back to the English label for untranslated categories.
+func (s *foodStore) categoryLabels(allowed bool, lang string) []string {
+	categories := s.notAllowedCategories
+	if allowed {
+		categories = s.allowedCategories
+	}
+	labels := make([]string, 0, len(categories))
+	for _, label := range categories {
+		labels = append(labels, s.localizedCategory(label, lang))
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func (s *foodStore) localizedCategory(label string, lang string) string {
+	if translated := s.categoryTranslations[categoryFileName(label)][lang]; translated != "" {
+		return translated
+	}
+	return label
+}
+
+// categoryMatches reports whether value names the category with the given
+// English label, as its file name, its English label or any translation.
+func (s *foodStore) categoryMatches(label string, value string) bool {
+	file := categoryFileName(label)
+	if value == file || strings.EqualFold(value, label) {
+		return true
+	}
+	for _, translated := range s.categoryTranslations[file] {
+		if strings.EqualFold(value, translated) {
+			return true
+		}
+	}
+	return false
+}
+
+// localizedName is the food's name in lang, or its English name.
+func (f *apiFood) localizedName(lang string) string {
+	if translation, ok := f.names[lang]; ok {
+		return translation.Name
+	}
+	return f.name
+}
+
+// loadCategoryTranslations reads the category label file from the data folder
+// root and adds its languages to the ones requests can select.
+func (s *foodStore) loadCategoryTranslations(path string) error {
+	translations, err := foodcatalog.LoadCategoryLabels(path)
+	if err != nil {
+		return err
+	}
+	s.categoryTranslations = translations
+	for _, labels := range translations {
+		for lang := range labels {
+			s.languages[lang] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+func newLocalizedTestStore(t *testing.T) {
+	t.Helper()
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "fruits.yaml", "- name: Cherries\n  names:\n    es: Cerezas\n    fr: Cerises\n- name: Guava\n")
+	writeTestCatalogFile(t, tempDir, "allowed", "herbs_spices.yaml", "- name: Basil\n  names:\n    es: Albahaca\n")
+	if err := os.WriteFile(filepath.Join(tempDir, foodcatalog.CategoryLabelsFileName), []byte("fruits:\n  es: Frutas\nherbs_spices:\n  es: Hierbas y especias\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store = newFoodStore(tempDir)
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func decodeLocalizedResponse(t *testing.T, handler http.HandlerFunc, request *http.Request) (responseData, string) {
+	t.Helper()
+	response := httptest.NewRecorder()
+	handler(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	var body responseData
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return body, response.Header().Get("Content-Language")
+}
+
+func TestSearchHandlerLocalizesNamesFromLangOrAcceptLanguage(t *testing.T) {
+	newLocalizedTestStore(t)
+
+	body, lang := decodeLocalizedResponse(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?key=cherries&type=searchbytext&lang=es", nil))
+	if lang != "es" || !reflect.DeepEqual(body.Allowed, []string{"Cerezas"}) {
+		t.Fatalf("expected Spanish name for an English query, got %q %#v", lang, body.Allowed)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/search?key=cerises&type=searchbytext", nil)
+	request.Header.Set("Accept-Language", "de-DE, fr-CA;q=0.8, es;q=0.5")
+	body, lang = decodeLocalizedResponse(t, searchHandler, request)
+	if lang != "fr" || !reflect.DeepEqual(body.Allowed, []string{"Cerises"}) {
+		t.Fatalf("expected the first translated Accept-Language, got %q %#v", lang, body.Allowed)
+	}
+
+	body, lang = decodeLocalizedResponse(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?key=guava&type=searchbytext&lang=es", nil))
+	if lang != "es" || !reflect.DeepEqual(body.Allowed, []string{"Guava"}) {
+		t.Fatalf("expected untranslated food to fall back to English, got %#v", body.Allowed)
+	}
+
+	body, lang = decodeLocalizedResponse(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?key=cerezas&type=searchbytext", nil))
+	if lang != "en" || len(body.Allowed) != 0 {
+		t.Fatalf("expected English search without translations, got %q %#v", lang, body.Allowed)
+	}
+
+	body, lang = decodeLocalizedResponse(t, searchHandler, httptest.NewRequest(http.MethodGet, "/search?key=cherries&type=searchbytext&lang=de", nil))
+	if lang != "en" || !reflect.DeepEqual(body.Allowed, []string{"Cherries"}) {
+		t.Fatalf("expected an untranslated lang parameter to fall back to English, got %q %#v", lang, body.Allowed)
+	}
+}
+
+func TestCategoryHandlersUseTranslatedLabels(t *testing.T) {
+	newLocalizedTestStore(t)
+
+	request := httptest.NewRequest(http.MethodGet, "/categories", nil)
+	request.Header.Set("Accept-Language", "es-MX")
+	body, _ := decodeLocalizedResponse(t, categoriesHandler, request)
+	if !reflect.DeepEqual(body.Allowed, []string{"Frutas", "Hierbas y especias"}) {
+		t.Fatalf("expected Spanish category labels, got %#v", body.Allowed)
+	}
+	body, _ = decodeLocalizedResponse(t, categoriesHandler, httptest.NewRequest(http.MethodGet, "/categories", nil))
+	if !reflect.DeepEqual(body.Allowed, []string{"Fruits", "Herbs and Spices"}) {
+		t.Fatalf("expected English category labels, got %#v", body.Allowed)
+	}
+
+	for _, sub := range []string{"Hierbas%20y%20especias", "herbs_spices", "Herbs%20and%20Spices"} {
+		body, _ = decodeLocalizedResponse(t, subCategoryHandler, httptest.NewRequest(http.MethodGet, "/subcategory?cat=Allowed&lang=es&sub="+sub, nil))
+		if !reflect.DeepEqual(body.Allowed, []string{"Albahaca"}) {
+			t.Fatalf("expected Spanish foods for %s, got %#v", sub, body.Allowed)
+		}
+	}
+
+	if label, ok := store.categoryLabel(true, strings.ToUpper("frutas")); !ok || label != "Fruits" {
+		t.Fatalf("expected translated suggestion category to map to its English label, got %q", label)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	logIPModeFull     = "full"
+	logIPModeTruncate = "truncate"
+	logIPModeHash     = "hash"
+	logIPModeDrop     = "drop"
+
+	searchKeyLogInline   = "inline"
+	searchKeyLogSeparate = "separate"
+)
+
+// logClientIP applies the configured IP privacy mode to the access log value.
+func logClientIP(privacy logPrivacyConfig, ip string) string {
+	switch strings.ToLower(strings.TrimSpace(privacy.IPMode)) {
+	case logIPModeTruncate:
+		return truncateIP(ip)
+	case logIPModeHash:
+		if privacy.IPHashKey == "" {
+			return "-"
+		}
+		return hashIP(privacy.IPHashKey, ip)
+	case logIPModeDrop:
+		return "-"
+	default:
+		return ip
+	}
+}
+
+// hashIP returns a short keyed HMAC-SHA256 of ip.
+func hashIP(key string, ip string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// truncateIP keeps the IPv4 /24 or IPv6 /48 network so abuse patterns stay
+// visible without identifying a single client.
+func truncateIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "-"
+	}
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+func logUserAgent(privacy logPrivacyConfig, r *http.Request) string {
+	if privacy.DropUserAgent {
+		return "-"
+	}
+	return r.UserAgent()
+}
+
+// separateSearchKeys reports whether search keys belong only in the search log.
+func separateSearchKeys(privacy logPrivacyConfig) bool {
+	return strings.EqualFold(strings.TrimSpace(privacy.SearchKeyMode), searchKeyLogSeparate)
+}
+
+// logRequestURI returns the request target for the access and error logs,
+// removing the search key when it is routed to the search log instead.
+func logRequestURI(privacy logPrivacyConfig, r *http.Request) string {
+	if !separateSearchKeys(privacy) || r.URL.Path != "/search" {
+		return r.URL.RequestURI()
+	}
+
+	query := r.URL.Query()
+	query.Del("key")
+	redacted := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return redacted.RequestURI()
+}
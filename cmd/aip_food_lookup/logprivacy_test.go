@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogClientIPAppliesPrivacyMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		privacy logPrivacyConfig
+		ip      string
+		want    string
+	}{
+		{name: "full", privacy: logPrivacyConfig{IPMode: logIPModeFull}, ip: "198.51.100.10", want: "198.51.100.10"},
+		{name: "truncate ipv4", privacy: logPrivacyConfig{IPMode: logIPModeTruncate}, ip: "198.51.100.10", want: "198.51.100.0"},
+		{name: "truncate ipv6", privacy: logPrivacyConfig{IPMode: logIPModeTruncate}, ip: "2001:db8:1234:5678::1", want: "2001:db8:1234::"},
+		{name: "hash without key", privacy: logPrivacyConfig{IPMode: logIPModeHash}, ip: "198.51.100.10", want: "-"},
+		{name: "drop", privacy: logPrivacyConfig{IPMode: logIPModeDrop}, ip: "198.51.100.10", want: "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logClientIP(tt.privacy, tt.ip); got != tt.want {
+				t.Fatalf("logClientIP(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+
+	hashed := logClientIP(logPrivacyConfig{IPMode: logIPModeHash, IPHashKey: "key"}, "198.51.100.10")
+	if len(hashed) != 16 || strings.Contains(hashed, "198") {
+		t.Fatalf("expected keyed IP hash, got %q", hashed)
+	}
+	if other := logClientIP(logPrivacyConfig{IPMode: logIPModeHash, IPHashKey: "other"}, "198.51.100.10"); other == hashed {
+		t.Fatal("expected IP hash to depend on the configured key")
+	}
+}
+
+func TestAccessLogMiddlewareRedactsSeparatedSearchKeys(t *testing.T) {
+	tempDir := t.TempDir()
+	config := appConfig{
+		AccessLogPath: filepath.Join(tempDir, "access.log"),
+		LogPrivacy: logPrivacyConfig{
+			IPMode:        logIPModeTruncate,
+			DropUserAgent: true,
+			SearchKeyMode: searchKeyLogSeparate,
+		},
+	}
+	handler := accessLogMiddleware(config, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/search?key=ghee&type=searchbytext", nil)
+	request.RemoteAddr = "198.51.100.10:12345"
+	request.Header.Set("User-Agent", "Dart/3.4")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	access, err := os.ReadFile(config.AccessLogPath)
+	if err != nil {
+		t.Fatalf("expected access log: %v", err)
+	}
+	if strings.Contains(string(access), "ghee") || strings.Contains(string(access), "Dart") || strings.Contains(string(access), "198.51.100.10") {
+		t.Fatalf("expected access log without user input, got %q", string(access))
+	}
+	if !strings.Contains(string(access), "198.51.100.0") || !strings.Contains(string(access), "/search?type=searchbytext") {
+		t.Fatalf("expected truncated IP and redacted target, got %q", string(access))
+	}
+}
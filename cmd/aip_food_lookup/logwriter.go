@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const rotatedLogTimeLayout = "20060102T150405Z"
+
+var rotatedLogSuffixPattern = regexp.MustCompile(`^\.[0-9]{8}T[0-9]{6}Z(-[0-9]+)?(\.gz)?$`)
+
+var (
+	logWriters     = make(map[string]*rotatingLogWriter)
+	logWritersLock sync.Mutex
+	logSettings    logRotationConfig
+	logErrorPath   string
+)
+
+// rotatingLogWriter keeps one log file open behind a buffer and rotates it by
+// size or age. Rotated files are renamed with a UTC timestamp suffix so
+// search_coverage can still glob them as access.log*. Compression and pruning
+// run in the background so requests only wait for the rename.
+type rotatingLogWriter struct {
+	mu       sync.Mutex
+	path     string
+	settings logRotationConfig
+	file     *os.File
+	writer   *bufio.Writer
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+	errorLog string
+
+	// housekeeping serializes background compression and pruning, and
+	// housekeepers tracks running passes so close can wait for them.
+	housekeeping sync.Mutex
+	housekeepers sync.WaitGroup
+}
+
+// configureLogWriters applies rotation settings to every log path, and names
+// the error log that background rotation failures go to. Open writers are
+// flushed and closed so the next line reopens with new settings.
+func configureLogWriters(settings logRotationConfig, errorLogPath string) {
+	logWritersLock.Lock()
+	open := make([]*rotatingLogWriter, 0, len(logWriters))
+	for path, writer := range logWriters {
+		open = append(open, writer)
+		delete(logWriters, path)
+	}
+	logSettings, logErrorPath = settings, errorLogPath
+	logWritersLock.Unlock()
+
+	// Closing waits for background passes, which may log errors through
+	// logWriterFor, so it happens outside the lock.
+	for _, writer := range open {
+		_ = writer.close()
+	}
+}
+
+func logWriterFor(path string) (*rotatingLogWriter, error) {
+	fullPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	logWritersLock.Lock()
+	defer logWritersLock.Unlock()
+
+	if writer, exists := logWriters[fullPath]; exists {
+		return writer, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, err
+	}
+
+	writer := &rotatingLogWriter{
+		path:     fullPath,
+		settings: logSettings,
+		now:      time.Now,
+		errorLog: logErrorPath,
+	}
+	logWriters[fullPath] = writer
+	return writer, nil
+}
+
+// flushLogWritersEvery pushes buffered lines to disk on a fixed interval.
+func flushLogWritersEvery(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		flushLogWriters()
+	}
+}
+
+// reopenLogWritersOnSignal reopens every log file on SIGHUP so an external
+// logrotate can move files away without copytruncate.
+func reopenLogWritersOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		reopenLogWriters()
+	}
+}
+
+func flushLogWriters() {
+	for _, writer := range currentLogWriters() {
+		_ = writer.flush()
+	}
+}
+
+func reopenLogWriters() {
+	for _, writer := range currentLogWriters() {
+		_ = writer.reopen()
+	}
+}
+
+func closeLogWriters() {
+	for _, writer := range currentLogWriters() {
+		_ = writer.close()
+	}
+}
+
+func currentLogWriters() []*rotatingLogWriter {
+	logWritersLock.Lock()
+	defer logWritersLock.Unlock()
+
+	writers := make([]*rotatingLogWriter, 0, len(logWriters))
+	for _, writer := range logWriters {
+		writers = append(writers, writer)
+	}
+	return writers
+}
+
+// writeLine appends one line, rotating first when the file is due. Lines are
+// flushed immediately unless a background flush interval is configured.
+func (w *rotatingLogWriter) writeLine(line string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.openLocked(); err != nil {
+		return err
+	}
+	if w.rotationDueLocked(int64(len(line) + 1)) {
+		if err := w.rotateLocked(); err != nil {
+			return err
+		}
+		if err := w.openLocked(); err != nil {
+			return err
+		}
+	}
+
+	count, err := fmt.Fprintln(w.writer, line)
+	w.size += int64(count)
+	if err != nil {
+		return err
+	}
+	if w.settings.FlushIntervalMilliseconds <= 0 {
+		return w.writer.Flush()
+	}
+	return nil
+}
+
+func (w *rotatingLogWriter) flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.writer == nil {
+		return nil
+	}
+	return w.writer.Flush()
+}
+
+func (w *rotatingLogWriter) reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.closeLocked(); err != nil {
+		return err
+	}
+	return w.openLocked()
+}
+
+// close flushes and closes the file, then waits for background compression
+// so shutdown does not leave half-written .gz files.
+func (w *rotatingLogWriter) close() error {
+	w.mu.Lock()
+	err := w.closeLocked()
+	w.mu.Unlock()
+
+	w.housekeepers.Wait()
+	return err
+}
+
+func (w *rotatingLogWriter) openLocked() error {
+	if w.file != nil {
+		return nil
+	}
+
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	w.size = info.Size()
+	w.openedAt = w.startedAt(info)
+	return nil
+}
+
+// startedAt estimates when the open file began so MaxAgeHours survives
+// restarts and reopens: the newest rotation's timestamp when there is one,
+// else the file's modification time, else now for a new, empty file.
+func (w *rotatingLogWriter) startedAt(info os.FileInfo) time.Time {
+	if info.Size() == 0 {
+		return w.now()
+	}
+	if rotated, err := rotatedLogFiles(w.path); err == nil && len(rotated) > 0 {
+		suffix := strings.TrimPrefix(rotated[0], w.path+".")
+		if len(suffix) >= len(rotatedLogTimeLayout) {
+			if rotatedAt, err := time.Parse(rotatedLogTimeLayout, suffix[:len(rotatedLogTimeLayout)]); err == nil {
+				return rotatedAt
+			}
+		}
+	}
+	return info.ModTime()
+}
+
+func (w *rotatingLogWriter) closeLocked() error {
+	if w.file == nil {
+		return nil
+	}
+
+	flushErr := w.writer.Flush()
+	closeErr := w.file.Close()
+	w.file = nil
+	w.writer = nil
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+func (w *rotatingLogWriter) rotationDueLocked(nextBytes int64) bool {
+	if !w.settings.Enabled || w.size == 0 {
+		return false
+	}
+	if w.settings.MaxSizeMB > 0 && w.size+nextBytes > int64(w.settings.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	if w.settings.MaxAgeHours > 0 && w.now().Sub(w.openedAt) >= time.Duration(w.settings.MaxAgeHours)*time.Hour {
+		return true
+	}
+	return false
+}
+
+// rotateLocked renames the current file and starts a background pass that
+// gzips it and prunes old rotations beyond the retention limits.
+func (w *rotatingLogWriter) rotateLocked() error {
+	if err := w.closeLocked(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(w.path, w.rotatedPath()); err != nil {
+		return err
+	}
+	settings, now, errorLog := w.settings, w.now(), w.errorLog
+	w.housekeepers.Add(1)
+	go func() {
+		defer w.housekeepers.Done()
+		if err := w.housekeep(settings, now); err != nil {
+			writeErrorLog(errorLog, fmt.Sprintf("error rotating log %s: %v", w.path, err))
+		}
+	}()
+	return nil
+}
+
+// housekeep compresses every uncompressed rotation, not just the newest, so
+// passes may finish in any order, then prunes.
+func (w *rotatingLogWriter) housekeep(settings logRotationConfig, now time.Time) error {
+	w.housekeeping.Lock()
+	defer w.housekeeping.Unlock()
+
+	if settings.Compress {
+		rotated, err := rotatedLogFiles(w.path)
+		if err != nil {
+			return err
+		}
+		for _, rotatedPath := range rotated {
+			if filepath.Ext(rotatedPath) == ".gz" {
+				continue
+			}
+			if err := compressLogFile(rotatedPath); err != nil {
+				return err
+			}
+		}
+	}
+	return pruneRotatedLogs(w.path, settings.MaxBackups, settings.MaxBackupAgeDays, now)
+}
+
+func (w *rotatingLogWriter) rotatedPath() string {
+	base := w.path + "." + w.now().UTC().Format(rotatedLogTimeLayout)
+	candidate := base
+	for i := 1; ; i++ {
+		_, plainErr := os.Stat(candidate)
+		_, gzipErr := os.Stat(candidate + ".gz")
+		if os.IsNotExist(plainErr) && os.IsNotExist(gzipErr) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func compressLogFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	compressed := gzip.NewWriter(target)
+	if _, err = io.Copy(compressed, source); err != nil {
+		compressed.Close()
+		target.Close()
+		return err
+	}
+	if err = compressed.Close(); err != nil {
+		target.Close()
+		return err
+	}
+	if err = target.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// pruneRotatedLogs keeps at most maxBackups rotated files and drops any older
+// than maxAgeDays. Zero disables the matching limit.
+func pruneRotatedLogs(path string, maxBackups int, maxAgeDays int, now time.Time) error {
+	if maxBackups <= 0 && maxAgeDays <= 0 {
+		return nil
+	}
+
+	rotated, err := rotatedLogFiles(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for index, rotatedPath := range rotated {
+		remove := maxBackups > 0 && index >= maxBackups
+		if !remove && maxAgeDays > 0 {
+			info, statErr := os.Stat(rotatedPath)
+			remove = statErr == nil && now.Sub(info.ModTime()) > time.Duration(maxAgeDays)*24*time.Hour
+		}
+		if remove {
+			if removeErr := os.Remove(rotatedPath); removeErr != nil {
+				errs = append(errs, removeErr.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("prune rotated logs: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// rotatedLogFiles lists rotations of path, newest first.
+func rotatedLogFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := []string{}
+	for _, match := range matches {
+		if rotatedLogSuffixPattern.MatchString(strings.TrimPrefix(match, path)) {
+			rotated = append(rotated, match)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(rotated)))
+	return rotated, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingLogWriterRotatesBySizeAndCompresses(t *testing.T) {
+	tempDir := t.TempDir()
+	now := time.Date(2026, 8, 18, 1, 29, 51, 0, time.UTC)
+	writer := &rotatingLogWriter{
+		path: filepath.Join(tempDir, "access.log"),
+		settings: logRotationConfig{
+			Enabled:    true,
+			MaxSizeMB:  1,
+			MaxBackups: 1,
+			Compress:   true,
+		},
+		now: func() time.Time { return now },
+	}
+	defer writer.close()
+
+	line := strings.Repeat("x", 600*1024)
+	for i := 0; i < 3; i++ {
+		if err := writer.writeLine(line); err != nil {
+			t.Fatalf("writeLine returned error: %v", err)
+		}
+		now = now.Add(time.Second)
+	}
+	writer.housekeepers.Wait()
+
+	rotated, err := rotatedLogFiles(writer.path)
+	if err != nil {
+		t.Fatalf("rotatedLogFiles returned error: %v", err)
+	}
+	if len(rotated) != 1 || !strings.HasSuffix(rotated[0], "access.log.20260818T012953Z.gz") {
+		t.Fatalf("expected one retained gzip rotation, got %#v", rotated)
+	}
+
+	file, err := os.Open(rotated[0])
+	if err != nil {
+		t.Fatalf("open rotated log: %v", err)
+	}
+	defer file.Close()
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("expected gzip rotated log: %v", err)
+	}
+	content, err := io.ReadAll(compressed)
+	if err != nil || string(content) != line+"\n" {
+		t.Fatalf("unexpected rotated content length=%d err=%v", len(content), err)
+	}
+}
+
+func TestRotatingLogWriterRotatesByAge(t *testing.T) {
+	tempDir := t.TempDir()
+	now := time.Date(2026, 8, 18, 1, 0, 0, 0, time.UTC)
+	writer := &rotatingLogWriter{
+		path:     filepath.Join(tempDir, "errors.log"),
+		settings: logRotationConfig{Enabled: true, MaxAgeHours: 24},
+		now:      func() time.Time { return now },
+	}
+	defer writer.close()
+
+	_ = writer.writeLine("first")
+	now = now.Add(25 * time.Hour)
+	_ = writer.writeLine("second")
+
+	if _, err := os.Stat(filepath.Join(tempDir, "errors.log.20260819T020000Z")); err != nil {
+		t.Fatalf("expected uncompressed age rotation: %v", err)
+	}
+	content, err := os.ReadFile(writer.path)
+	if err != nil || string(content) != "second\n" {
+		t.Fatalf("expected fresh log file, got %q err=%v", string(content), err)
+	}
+}
+
+func TestRotatingLogWriterAgeSurvivesRestart(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "errors.log")
+	if err := os.WriteFile(path+".20260818T010000Z", []byte("older\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("before restart\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 8, 19, 2, 0, 0, 0, time.UTC)
+	writer := &rotatingLogWriter{
+		path:     path,
+		settings: logRotationConfig{Enabled: true, MaxAgeHours: 24},
+		now:      func() time.Time { return now },
+	}
+	defer writer.close()
+
+	_ = writer.writeLine("after restart")
+
+	if content, err := os.ReadFile(path + ".20260819T020000Z"); err != nil || string(content) != "before restart\n" {
+		t.Fatalf("expected the file started at the last rotation to rotate, got %q err=%v", string(content), err)
+	}
+}
+
+func TestRotatingLogWriterBuffersAndReopens(t *testing.T) {
+	tempDir := t.TempDir()
+	writer := &rotatingLogWriter{
+		path:     filepath.Join(tempDir, "access.log"),
+		settings: logRotationConfig{FlushIntervalMilliseconds: 1000},
+		now:      time.Now,
+	}
+	defer writer.close()
+
+	_ = writer.writeLine("buffered")
+	if content, _ := os.ReadFile(writer.path); len(content) != 0 {
+		t.Fatalf("expected line to stay buffered, got %q", string(content))
+	}
+
+	movedPath := filepath.Join(tempDir, "access.log.1")
+	if err := os.Rename(writer.path, movedPath); err != nil {
+		t.Fatalf("move log: %v", err)
+	}
+	if err := writer.reopen(); err != nil {
+		t.Fatalf("reopen returned error: %v", err)
+	}
+	_ = writer.writeLine("after reopen")
+	_ = writer.flush()
+
+	moved, _ := os.ReadFile(movedPath)
+	current, _ := os.ReadFile(writer.path)
+	if string(moved) != "buffered\n" || string(current) != "after reopen\n" {
+		t.Fatalf("unexpected reopen result moved=%q current=%q", string(moved), string(current))
+	}
+}
+
+func TestRotatingLogWriterLogsHousekeepingErrors(t *testing.T) {
+	tempDir := t.TempDir()
+	errorLog := filepath.Join(tempDir, "errors.log")
+	now := time.Date(2026, 8, 18, 1, 29, 51, 0, time.UTC)
+	writer := &rotatingLogWriter{
+		path:     filepath.Join(tempDir, "access.log"),
+		settings: logRotationConfig{Enabled: true, MaxSizeMB: 1, Compress: true},
+		now:      func() time.Time { return now },
+		errorLog: errorLog,
+	}
+	defer writer.close()
+	// A directory named like a rotation cannot be compressed.
+	if err := os.Mkdir(writer.path+".20260101T000000Z", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	line := strings.Repeat("x", 600*1024)
+	for i := 0; i < 2; i++ {
+		if err := writer.writeLine(line); err != nil {
+			t.Fatalf("writeLine returned error: %v", err)
+		}
+		now = now.Add(time.Second)
+	}
+	writer.housekeepers.Wait()
+	flushLogWriters()
+
+	content, _ := os.ReadFile(errorLog)
+	if !strings.Contains(string(content), "error rotating log "+writer.path) {
+		t.Fatalf("expected the rotation error in the error log, got %q", content)
+	}
+}
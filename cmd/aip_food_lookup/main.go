@@ -1,19 +1,20 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"path"
+	"os/signal"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
 	"github.com/CalypsoSys/godoublemetaphone/pkg/godoublemetaphone"
@@ -25,7 +26,9 @@ const (
 	allowedNotAllowedMaxLen = 50
 	feedbackMessageMaxLen   = 2000
 	feedbackFieldMaxLen     = 200
+	suggestionNoteMaxLen    = 500
 	adminReloadPath         = "/admin/reload"
+	shutdownTimeout         = 10 * time.Second
 )
 
 type responseData struct {
@@ -34,8 +37,13 @@ type responseData struct {
 }
 
 type requestData struct {
-	InputText string `json:"inputText"`
-	Allowed   bool   `json:"allowed"`
+	InputText     string `json:"inputText"`
+	Allowed       bool   `json:"allowed"`
+	Category      string `json:"category"`
+	Note          string `json:"note"`
+	CatalogName   string `json:"-"`
+	CatalogStatus string `json:"-"`
+	ClientIP      string `json:"-"`
 }
 
 type adminReloadResponse struct {
@@ -46,44 +54,53 @@ type adminReloadResponse struct {
 	Error                string `json:"error,omitempty"`
 }
 
+// feedbackRequest is the /feedback body plus the ID, received time and client
+// headers the server adds before handing it to the sinks.
 type feedbackRequest struct {
-	Name    string `json:"name"`
-	Email   string `json:"email"`
-	Subject string `json:"subject"`
-	Message string `json:"message"`
-	Source  string `json:"source"`
+	ID         string    `json:"id,omitempty"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email"`
+	Subject    string    `json:"subject"`
+	Message    string    `json:"message"`
+	Source     string    `json:"source"`
+	AppVersion string    `json:"appVersion,omitempty"`
+	Client     string    `json:"client,omitempty"`
 }
 
 type apiFood struct {
 	allowed                 bool
 	name                    string
 	aliases                 []string
+	names                   map[string]foodcatalog.LocalizedName
 	primaryShortMetaphone   uint16
 	alternateShortMetaphone uint16
 	category                string
 }
 
 type foodStore struct {
-	allowedCategories     []string
-	notAllowedCategories  []string
-	allowedSuggestions    map[string]bool
-	notAllowedSuggestions map[string]bool
-	dataFolder            string
-	errorLogPath          string
-	feedbackSink          feedbackSink
-	suggestionSink        suggestionSink
-	nameFoods             map[string]*apiFood
+	allowedCategories    []string
+	notAllowedCategories []string
+	suggestions          *suggestionStore
+	disputes             *suggestionStore
+	dataFolder           string
+	errorLogPath         string
+	searchLogPath        string
+	feedbackSink         feedbackSink
+	suggestionSink       suggestionSink
+	contentFilter        *contentFilter
+	feedbackPath         string
+	nameFoods            map[string]*apiFood
+	singularFoods        map[string]*apiFood
+	languages            map[string]bool
+	categoryTranslations map[string]map[string]string
+	matchOptions         foodcatalog.MatchOptions
 }
 
 type feedbackSink interface {
 	submitFeedback(feedbackRequest) error
 }
 
-type fileFeedbackSink struct {
-	dataFolder string
-	filePath   string
-}
-
 type suggestionSink interface {
 	submitSuggestion(requestData) error
 }
@@ -93,26 +110,53 @@ var (
 	storeLock sync.RWMutex
 )
 
-// newFoodStore initializes the in-memory index and suggestion caches.
+// newFoodStore initializes the in-memory index and the suggestion store.
 func newFoodStore(dataFolder string) *foodStore {
 	return &foodStore{
-		allowedCategories:     []string{},
-		notAllowedCategories:  []string{},
-		allowedSuggestions:    make(map[string]bool),
-		notAllowedSuggestions: make(map[string]bool),
-		dataFolder:            dataFolder,
-		feedbackSink:          fileFeedbackSink{dataFolder: dataFolder},
-		nameFoods:             make(map[string]*apiFood),
+		allowedCategories:    []string{},
+		notAllowedCategories: []string{},
+		suggestions:          newSuggestionStore(suggestionStorePath(dataFolder, "")),
+		disputes:             newSuggestionStore(disputeStorePath(suggestionStorePath(dataFolder, ""))),
+		dataFolder:           dataFolder,
+		nameFoods:            make(map[string]*apiFood),
+		singularFoods:        make(map[string]*apiFood),
+		languages:            make(map[string]bool),
+		categoryTranslations: make(map[string]map[string]string),
+		matchOptions:         foodcatalog.DefaultMatchOptions(),
 	}
 }
 
 func main() {
 	config := loadConfig()
+	configureLogWriters(config.LogRotation, config.ErrorLogPath)
+	go flushLogWritersEvery(time.Duration(config.LogRotation.FlushIntervalMilliseconds) * time.Millisecond)
+	go reopenLogWritersOnSignal()
 
 	store = newFoodStore(config.DataFolder)
 	store.errorLogPath = config.ErrorLogPath
-	store.feedbackSink = newFeedbackSink(config)
-	store.suggestionSink = newSuggestionSink(config)
+	if config.LogPrivacy.SearchEvents {
+		store.searchLogPath = config.LogPrivacy.SearchLogPath
+	}
+	suggestionsPath := suggestionStorePath(config.DataFolder, config.SuggestionsPath)
+	store.suggestions = newSuggestionStore(suggestionsPath)
+	store.disputes = newSuggestionStore(disputeStorePath(suggestionsPath))
+	if config.LogPrivacy.IPHashKey != "" {
+		store.suggestions.clientKey = config.LogPrivacy.IPHashKey
+		store.disputes.clientKey = config.LogPrivacy.IPHashKey
+	}
+	outbox := newSlackOutbox(config)
+	if outbox != nil {
+		go outbox.run()
+	}
+	store.feedbackSink = newFeedbackSink(config, outbox)
+	store.suggestionSink = newSuggestionSink(config, outbox)
+	store.contentFilter = newContentFilter(config)
+	store.feedbackPath = feedbackFilePath(config.DataFolder, config.FeedbackJSONLPath)
+	if err := config.Matching.Validate(); err != nil {
+		fmt.Println("invalid matching options, using defaults:", err)
+	} else {
+		store.matchOptions = config.Matching
+	}
 	if err := store.processDirectory(config.DataFolder); err != nil {
 		fmt.Println("error loading data:", err)
 	}
@@ -129,8 +173,30 @@ func main() {
 		IdleTimeout:       60 * time.Second,
 	}
 
-	err := server.ListenAndServe()
-	fmt.Println(err)
+	stopped := make(chan struct{})
+	go shutdownOnSignal(server, stopped)
+	if err := server.ListenAndServe(); errors.Is(err, http.ErrServerClosed) {
+		<-stopped
+	} else {
+		fmt.Println(err)
+	}
+	closeLogWriters()
+}
+
+// shutdownOnSignal stops the server gracefully on SIGTERM or SIGINT so main
+// can flush buffered log lines before the process exits. It closes stopped
+// once in-flight requests have finished or shutdownTimeout has passed.
+func shutdownOnSignal(server *http.Server, stopped chan<- struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	<-signals
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		fmt.Println("error shutting down:", err)
+	}
+	close(stopped)
 }
 
 func registerHandlers(mux *http.ServeMux) {
@@ -141,6 +207,16 @@ func registerHandlers(mux *http.ServeMux) {
 	mux.HandleFunc("/categories", categoriesHandler)
 	mux.HandleFunc("/subcategory", subCategoryHandler)
 	mux.HandleFunc(adminReloadPath, adminReloadHandler)
+	mux.HandleFunc(adminSuggestionsPath, adminSuggestionsHandler)
+	mux.HandleFunc(adminSuggestionsAcceptPath, adminAcceptSuggestionHandler)
+	mux.HandleFunc(adminSuggestionsRejectPath, adminRejectSuggestionHandler)
+	mux.HandleFunc(adminDisputesPath, adminDisputesHandler)
+	mux.HandleFunc(adminDisputesAcceptPath, adminAcceptDisputeHandler)
+	mux.HandleFunc(adminDisputesRejectPath, adminRejectDisputeHandler)
+	mux.HandleFunc(adminSinksPath, adminSinksHandler)
+	mux.HandleFunc(adminFeedbackPath, adminFeedbackHandler)
+	mux.HandleFunc(adminFeedbackHandlePath, adminHandleFeedbackHandler)
+	mux.HandleFunc(adminSearchPath, adminSearchHandler)
 }
 
 // healthHandler gives load balancers and local smoke tests a simple API check.
@@ -159,26 +235,42 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	_, _ = fmt.Fprint(w, "AIP Food Lookup API")
 }
 
-// feedbackHandler validates app feedback and stores it for later Slack plumbing.
+// feedbackHandler validates app feedback, stores it and notifies the feedback
+// sinks.
 func feedbackHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	currentStore := getStore()
 	var request feedbackRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	honeypot, err := currentStore.contentFilter.decodeSubmission(r, &request)
+	if err != nil {
 		http.Error(w, "Error decoding JSON", http.StatusBadRequest)
 		return
 	}
 
+	request.ID = newFeedbackID()
+	request.ReceivedAt = time.Now().UTC()
+	request.AppVersion = r.Header.Get("X-AIP-App-Version")
+	request.Client = r.Header.Get("X-AIP-Client")
 	normalized, err := normalizeFeedback(request)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	currentStore := getStore()
-	if err := currentStore.feedbackSink.submitFeedback(normalized); err != nil {
+	if currentStore.contentFilter.intercept(w, submission{
+		Kind:         "feedback",
+		ClientIP:     remoteIP(r),
+		Honeypot:     honeypot,
+		Text:         []string{normalized.Name, normalized.Subject, normalized.Message},
+		DuplicateKey: duplicateText(remoteIP(r), normalized.Message),
+		Payload:      normalized,
+	}) {
+		return
+	}
+	if err := currentStore.submitFeedback(normalized); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -187,8 +279,10 @@ func feedbackHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// searchHandler returns matching allowed and not allowed foods for a query.
+// searchHandler returns matching allowed and not allowed foods for a query
+// and records the result counts in the search analytics log.
 func searchHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
 	key := strings.TrimSpace(r.URL.Query().Get("key"))
 	if key == "" {
 		http.Error(w, "Key parameter is missing", http.StatusBadRequest)
@@ -196,29 +290,59 @@ func searchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	currentStore := getStore()
-	response := currentStore.match(key, r.URL.Query().Get("type"))
+	typeSearch := r.URL.Query().Get("type")
+	lang := currentStore.requestLanguage(r)
+	response := currentStore.match(key, typeSearch, lang)
+	setLanguageHeaders(w, lang)
 	commonResponse(w, response)
+	writeSearchEvent(currentStore.searchLogPath, newSearchEvent(r, key, typeSearch, response, start))
 }
 
-// suggestHandler records user suggestions after basic length and ASCII cleanup.
+// suggestHandler records user suggestions after Unicode cleanup, which keeps
+// accents, and rune-based length checks; matching later folds the text. It
+// also checks the optional category hint against the suggested status.
 func suggestHandler(w http.ResponseWriter, r *http.Request) {
+	currentStore := getStore()
 	var request requestData
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	honeypot, err := currentStore.contentFilter.decodeSubmission(r, &request)
+	if err != nil {
 		http.Error(w, "Error decoding JSON", http.StatusBadRequest)
 		return
 	}
 
-	request.InputText = stripNonASCII(strings.TrimSpace(request.InputText))
-	if len(request.InputText) > allowedNotAllowedMaxLen {
+	request.InputText = foodcatalog.CleanText(request.InputText)
+	if utf8.RuneCountInString(request.InputText) > allowedNotAllowedMaxLen {
 		http.Error(w, "Suggestion too long", http.StatusBadRequest)
 		return
 	}
-	if len(request.InputText) < allowedNotAllowedMinLen {
+	if utf8.RuneCountInString(request.InputText) < allowedNotAllowedMinLen {
 		http.Error(w, "Suggestion too short", http.StatusBadRequest)
 		return
 	}
-	currentStore := getStore()
-	if err := currentStore.submitSuggestion(request.Allowed, request.InputText); err != nil {
+	request.Note = foodcatalog.CleanText(request.Note)
+	if utf8.RuneCountInString(request.Note) > suggestionNoteMaxLen {
+		http.Error(w, "Suggestion note too long", http.StatusBadRequest)
+		return
+	}
+	if request.Category = strings.TrimSpace(request.Category); request.Category != "" {
+		category, ok := currentStore.categoryLabel(request.Allowed, request.Category)
+		if !ok {
+			http.Error(w, "Unknown category", http.StatusBadRequest)
+			return
+		}
+		request.Category = category
+	}
+	if currentStore.contentFilter.intercept(w, submission{
+		Kind:         "suggestion",
+		ClientIP:     remoteIP(r),
+		Honeypot:     honeypot,
+		Text:         []string{request.InputText, request.Note},
+		DuplicateKey: duplicateText(remoteIP(r), request.InputText, suggestionStatus(request.Allowed)),
+		Payload:      request,
+	}) {
+		return
+	}
+	if err := currentStore.submitSuggestion(request, remoteIP(r)); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -227,12 +351,15 @@ func suggestHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// categoriesHandler returns the available top-level allowed/not allowed groups.
+// categoriesHandler returns the available top-level allowed/not allowed
+// groups, labeled in the request language.
 func categoriesHandler(w http.ResponseWriter, r *http.Request) {
 	currentStore := getStore()
+	lang := currentStore.requestLanguage(r)
+	setLanguageHeaders(w, lang)
 	commonResponse(w, responseData{
-		Allowed:    currentStore.allowedCategories,
-		NotAllowed: currentStore.notAllowedCategories,
+		Allowed:    currentStore.categoryLabels(true, lang),
+		NotAllowed: currentStore.categoryLabels(false, lang),
 	})
 }
 
@@ -251,7 +378,9 @@ func subCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	currentStore := getStore()
-	response := currentStore.subCategory(category, subCategory)
+	lang := currentStore.requestLanguage(r)
+	response := currentStore.subCategory(category, subCategory, lang)
+	setLanguageHeaders(w, lang)
 	commonResponse(w, response)
 }
 
@@ -293,8 +422,14 @@ func reloadFoodStore() (*foodStore, error) {
 
 	nextStore := newFoodStore(dataFolder)
 	nextStore.errorLogPath = currentStore.errorLogPath
+	nextStore.searchLogPath = currentStore.searchLogPath
+	nextStore.suggestions = currentStore.suggestions
+	nextStore.disputes = currentStore.disputes
 	nextStore.feedbackSink = currentStore.feedbackSink
 	nextStore.suggestionSink = currentStore.suggestionSink
+	nextStore.contentFilter = currentStore.contentFilter
+	nextStore.feedbackPath = currentStore.feedbackPath
+	nextStore.matchOptions = currentStore.matchOptions
 	if err := nextStore.processDirectory(dataFolder); err != nil {
 		return nil, err
 	}
@@ -334,13 +469,20 @@ func commonResponse(w http.ResponseWriter, response responseData) {
 	_, _ = w.Write(jsonData)
 }
 
-// match combines prefix matching with Double Metaphone sound matching.
-func (s *foodStore) match(name string, typeSearch string) responseData {
+// match combines prefix matching with Double Metaphone sound matching, naming
+// foods in lang and matching both their translated and English names.
+func (s *foodStore) match(name string, typeSearch string, lang string) responseData {
+	return s.matchWith(s.matchOptions, name, typeSearch, lang)
+}
+
+// matchWith runs match with the given thresholds instead of the configured
+// ones, for admin tuning.
+func (s *foodStore) matchWith(options foodcatalog.MatchOptions, name string, typeSearch string, lang string) responseData {
 	foods := make([]foodcatalog.Food, 0, len(s.nameFoods))
 	for _, food := range s.nameFoods {
-		foods = append(foods, foodcatalog.Food{Allowed: food.allowed, Name: food.name, Aliases: food.aliases, PrimaryShortMetaphone: food.primaryShortMetaphone, AlternateShortMetaphone: food.alternateShortMetaphone})
+		foods = append(foods, foodcatalog.Food{Allowed: food.allowed, Name: food.name, Aliases: food.aliases, Names: food.names, PrimaryShortMetaphone: food.primaryShortMetaphone, AlternateShortMetaphone: food.alternateShortMetaphone})
 	}
-	result := foodcatalog.Match(foods, name, typeSearch)
+	result := options.Match(foodcatalog.Localize(foods, lang), name, typeSearch)
 	return responseData{Allowed: result.Allowed, NotAllowed: result.NotAllowed}
 }
 
@@ -348,8 +490,26 @@ func spellingDistanceAllowed(query string, candidate string) bool {
 	return foodcatalog.SpellingDistanceAllowed(query, candidate)
 }
 
+// categoryLabel returns the English /categories label matching category for
+// the given status, compared case-insensitively with the English and
+// translated labels.
+func (s *foodStore) categoryLabel(allowed bool, category string) (string, bool) {
+	categories := s.notAllowedCategories
+	if allowed {
+		categories = s.allowedCategories
+	}
+	for _, label := range categories {
+		if s.categoryMatches(label, category) {
+			return label, true
+		}
+	}
+	return "", false
+}
+
 // subCategory filters loaded foods by MAUI-compatible category route values.
-func (s *foodStore) subCategory(category string, subCategory string) responseData {
+// The subcategory may be a file name or an English or translated label; foods
+// are named in lang.
+func (s *foodStore) subCategory(category string, subCategory string, lang string) responseData {
 	response := responseData{
 		Allowed:    []string{},
 		NotAllowed: []string{},
@@ -366,10 +526,13 @@ func (s *foodStore) subCategory(category string, subCategory string) responseDat
 	} else {
 		return response
 	}
+	if label, ok := s.categoryLabel(allowed, subCategory); ok {
+		subCategory = categoryFileName(label)
+	}
 
 	for _, food := range s.nameFoods {
 		if food.allowed == allowed && food.category == subCategory {
-			*output = append(*output, food.name)
+			*output = append(*output, food.localizedName(lang))
 		}
 	}
 	sort.Strings(*output)
@@ -378,11 +541,13 @@ func (s *foodStore) subCategory(category string, subCategory string) responseDat
 
 // normalizeFeedback applies minimal privacy-conscious validation and cleanup.
 func normalizeFeedback(request feedbackRequest) (feedbackRequest, error) {
-	request.Name = stripNonASCII(strings.TrimSpace(request.Name))
-	request.Email = stripNonASCII(strings.TrimSpace(request.Email))
-	request.Subject = stripNonASCII(strings.TrimSpace(request.Subject))
-	request.Message = stripNonASCII(strings.TrimSpace(request.Message))
-	request.Source = stripNonASCII(strings.TrimSpace(request.Source))
+	request.Name = foodcatalog.CleanText(request.Name)
+	request.Email = foodcatalog.CleanText(request.Email)
+	request.Subject = foodcatalog.CleanText(request.Subject)
+	request.Message = foodcatalog.CleanText(request.Message)
+	request.Source = foodcatalog.CleanText(request.Source)
+	request.AppVersion = truncateFeedbackHeader(request.AppVersion)
+	request.Client = truncateFeedbackHeader(request.Client)
 
 	if request.Message == "" {
 		return request, errors.New("Message is required")
@@ -390,13 +555,13 @@ func normalizeFeedback(request feedbackRequest) (feedbackRequest, error) {
 	if request.Name == "" && request.Email == "" {
 		return request, errors.New("Name or email is required")
 	}
-	if len(request.Name) > feedbackFieldMaxLen ||
-		len(request.Email) > feedbackFieldMaxLen ||
-		len(request.Subject) > feedbackFieldMaxLen ||
-		len(request.Source) > feedbackFieldMaxLen {
+	if utf8.RuneCountInString(request.Name) > feedbackFieldMaxLen ||
+		utf8.RuneCountInString(request.Email) > feedbackFieldMaxLen ||
+		utf8.RuneCountInString(request.Subject) > feedbackFieldMaxLen ||
+		utf8.RuneCountInString(request.Source) > feedbackFieldMaxLen {
 		return request, errors.New("Feedback field too long")
 	}
-	if len(request.Message) > feedbackMessageMaxLen {
+	if utf8.RuneCountInString(request.Message) > feedbackMessageMaxLen {
 		return request, errors.New("Feedback message too long")
 	}
 	if request.Subject == "" {
@@ -452,20 +617,31 @@ func (s *foodStore) processFile(filePath string) error {
 	for _, entry := range entries {
 		name, aliases := entry.Name, entry.Aliases
 
-		nameLower := strings.ToLower(name)
-		if _, exists := s.nameFoods[nameLower]; exists {
+		key := foodcatalog.Fold(name)
+		if _, exists := s.nameFoods[key]; exists {
 			continue
 		}
 
-		sdm := godoublemetaphone.NewShortDoubleMetaphone(name)
-		s.nameFoods[nameLower] = &apiFood{
+		sdm := godoublemetaphone.NewShortDoubleMetaphone(key)
+		food := &apiFood{
 			allowed:                 allowedFolder == "allowed",
 			name:                    name,
 			aliases:                 aliases,
+			names:                   entry.Names,
 			primaryShortMetaphone:   sdm.PrimaryShortKey(),
 			alternateShortMetaphone: sdm.AlternateShortKey(),
 			category:                category,
 		}
+		s.nameFoods[key] = food
+		// Plural and singular spellings, such as Cherries and Cherry, stay
+		// separate foods; search_coverage duplicates reports them. The first
+		// one loaded answers catalogFood lookups for both.
+		if singular := foodcatalog.Singular(name); s.singularFoods[singular] == nil {
+			s.singularFoods[singular] = food
+		}
+		for lang := range entry.Names {
+			s.languages[lang] = true
+		}
 	}
 
 	return nil
@@ -478,6 +654,9 @@ func (s *foodStore) processDirectory(directoryPath string) error {
 			return err
 		}
 
+		if filepath.Base(p) == foodcatalog.CategoryLabelsFileName {
+			return s.loadCategoryTranslations(p)
+		}
 		if !info.IsDir() && (filepath.Ext(p) == ".dat" || filepath.Ext(p) == ".yaml") {
 			if filepath.Ext(p) == ".dat" {
 				if _, statErr := os.Stat(strings.TrimSuffix(p, ".dat") + ".yaml"); statErr == nil {
@@ -488,10 +667,10 @@ func (s *foodStore) processDirectory(directoryPath string) error {
 		}
 
 		if filepath.Base(p) == "suggested_allowed.txt" {
-			_ = loadCurrentSuggested(p, s.allowedSuggestions)
+			_ = s.suggestions.importLegacy(p, true)
 		}
 		if filepath.Base(p) == "suggested_not_allowed.txt" {
-			_ = loadCurrentSuggested(p, s.notAllowedSuggestions)
+			_ = s.suggestions.importLegacy(p, false)
 		}
 		return nil
 	})
@@ -500,128 +679,79 @@ func (s *foodStore) processDirectory(directoryPath string) error {
 	return err
 }
 
-// submitFeedback writes one JSON line until a Slack sink is added later.
-func (s fileFeedbackSink) submitFeedback(request feedbackRequest) error {
-	filePath := s.filePath
-	if filePath == "" {
-		filePath = path.Join(s.dataFolder, "feedback.jsonl")
-	}
-	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-		return err
-	}
-
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	payload, err := json.Marshal(request)
+// submitFeedback writes the feedback to the feedback file, the admin triage
+// queue, and then sends notifications through the feedback sink chain. It
+// fails only when neither the file nor any notification sink accepted it.
+func (s *foodStore) submitFeedback(request feedbackRequest) error {
+	err := appendFeedbackRecord(feedbackFilePath(s.dataFolder, s.feedbackPath), feedbackRecord{
+		feedbackRequest: request,
+		Status:          feedbackStatusNew,
+	})
 	if err != nil {
-		return err
+		writeErrorLog(s.errorLogPath, fmt.Sprintf("file feedback failed: %v", err))
 	}
-	if _, err = file.Write(append(payload, '\n')); err != nil {
-		return err
+	if s.feedbackSink != nil && s.feedbackSink.submitFeedback(request) == nil {
+		return nil
 	}
-	return nil
+	return err
 }
 
-// submitSuggestion attempts local storage and Slack notification independently.
-func (s *foodStore) submitSuggestion(allowed bool, text string) error {
-	request := requestData{
-		InputText: text,
-		Allowed:   allowed,
-	}
-
-	localErr := s.appendSuggestion(allowed, text)
-	if localErr != nil {
-		writeErrorLog(s.errorLogPath, fmt.Sprintf("suggestion file write failed: %v", localErr))
+// truncateFeedbackHeader cleans an optional client header, cutting rather
+// than rejecting long values since users cannot fix them.
+func truncateFeedbackHeader(value string) string {
+	value = foodcatalog.CleanText(value)
+	if utf8.RuneCountInString(value) > feedbackFieldMaxLen {
+		return string([]rune(value)[:feedbackFieldMaxLen])
 	}
+	return value
+}
 
-	if s.suggestionSink == nil {
-		return localErr
+// submitSuggestion records the suggestion in the suggestion store and then
+// sends notifications through the suggestion sink chain. It fails only when
+// neither the store nor any notification sink accepted the suggestion.
+// Suggestions claiming the opposite status of a catalog food are disputes.
+func (s *foodStore) submitSuggestion(request requestData, clientIP string) error {
+	if food, exists := s.catalogFood(request.InputText); exists && food.allowed != request.Allowed {
+		request.CatalogName = food.name
+		request.CatalogStatus = suggestionStatus(food.allowed)
 	}
+	request.ClientIP = clientIP
 
-	slackErr := s.suggestionSink.submitSuggestion(request)
-	if slackErr != nil {
-		writeErrorLog(s.errorLogPath, fmt.Sprintf("slack suggestion failed: %v", slackErr))
+	err := s.appendSuggestion(request, clientIP)
+	if err != nil {
+		writeErrorLog(s.errorLogPath, fmt.Sprintf("file suggestion failed: %v", err))
 	}
-
-	if localErr == nil || slackErr == nil {
+	if s.suggestionSink != nil && s.suggestionSink.submitSuggestion(request) == nil {
 		return nil
 	}
-	return errors.Join(localErr, slackErr)
+	return err
 }
 
-// appendSuggestion persists a new user suggestion if it is not already known.
-func (s *foodStore) appendSuggestion(allowed bool, text string) error {
-	key := strings.ToLower(strings.TrimSpace(text))
-	if _, exists := s.nameFoods[key]; exists {
-		return nil
-	}
-	if _, exists := s.allowedSuggestions[key]; exists {
-		return nil
-	}
-	if _, exists := s.notAllowedSuggestions[key]; exists {
-		return nil
-	}
-
-	var fileName string
-	var cache map[string]bool
-	if allowed {
-		fileName = "suggested_allowed.txt"
-		cache = s.allowedSuggestions
-	} else {
-		fileName = "suggested_not_allowed.txt"
-		cache = s.notAllowedSuggestions
-	}
-
-	if len(cache) > allowedNotAllowedLimit {
-		return errors.New("suggestion limit exceeded")
+// appendSuggestion records a user suggestion vote, or a status dispute vote
+// when the text names a catalog food.
+func (s *foodStore) appendSuggestion(request requestData, clientIP string) error {
+	vote := suggestionVote{
+		Text:     request.InputText,
+		Allowed:  request.Allowed,
+		ClientIP: clientIP,
+		Category: request.Category,
+		Note:     request.Note,
 	}
-
-	filePath := path.Join(s.dataFolder, fileName)
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	if _, err = fmt.Fprintln(writer, key); err != nil {
-		return err
-	}
-	if err = writer.Flush(); err != nil {
-		return err
+	if food, exists := s.catalogFood(vote.Text); exists {
+		vote.Food, vote.CatalogStatus = food.name, suggestionStatus(food.allowed)
+		return s.disputes.record(vote)
 	}
-
-	cache[key] = true
-	return nil
+	return s.suggestions.record(vote)
 }
 
-// stripNonASCII keeps suggestion storage simple and compatible with old data.
-func stripNonASCII(input string) string {
-	regex := regexp.MustCompile("[^[:ascii:]]")
-	return regex.ReplaceAllString(input, "")
-}
-
-// loadCurrentSuggested hydrates duplicate-detection caches for suggestion files.
-func loadCurrentSuggested(filePath string, cache map[string]bool) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
-		if line != "" {
-			cache[line] = true
-		}
+// catalogFood finds the catalog food named by text, ignoring case, accents
+// and plural form.
+func (s *foodStore) catalogFood(text string) (*apiFood, bool) {
+	if food, exists := s.nameFoods[foodcatalog.Fold(text)]; exists {
+		return food, true
 	}
-
-	return scanner.Err()
+	food, exists := s.singularFoods[foodcatalog.Singular(text)]
+	return food, exists
 }
 
 // convertPhrase maps category filenames to the labels used by the MAUI app.
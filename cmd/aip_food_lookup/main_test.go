@@ -25,7 +25,7 @@ func TestProcessDirectoryLoadsCategoriesAndSearchData(t *testing.T) {
 		t.Fatal("expected not allowed categories to load")
 	}
 
-	result := testStore.match("App", "searchbytext")
+	result := testStore.match("App", "searchbytext", "en")
 	if !contains(result.Allowed, "Apples") {
 		t.Fatalf("expected Apples in allowed search results, got %#v", result.Allowed)
 	}
@@ -47,7 +47,7 @@ func TestCatalogClassifiesAlmondFlourAndMealAsNotAllowed(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.want, func(t *testing.T) {
-			result := testStore.match(tt.query, "searchbytext")
+			result := testStore.match(tt.query, "searchbytext", "en")
 			if !contains(result.NotAllowed, tt.want) {
 				t.Fatalf("expected %q in not allowed search results, got %#v", tt.want, result.NotAllowed)
 			}
@@ -57,7 +57,7 @@ func TestCatalogClassifiesAlmondFlourAndMealAsNotAllowed(t *testing.T) {
 		})
 	}
 
-	misspelled := testStore.match("grench fries", "")
+	misspelled := testStore.match("grench fries", "", "en")
 	if !contains(misspelled.NotAllowed, "French Fries") {
 		t.Fatalf("expected French Fries for misspelled search, got %#v", misspelled.NotAllowed)
 	}
@@ -81,7 +81,7 @@ func TestCatalogClassifiesCommonCoreFoodsAsNotAllowed(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.want, func(t *testing.T) {
-			result := testStore.match(tt.query, "searchbytext")
+			result := testStore.match(tt.query, "searchbytext", "en")
 			if !contains(result.NotAllowed, tt.want) {
 				t.Fatalf("expected %q in not allowed search results, got %#v", tt.want, result.NotAllowed)
 			}
@@ -106,7 +106,7 @@ func TestSuggestHandlerWritesValidSuggestion(t *testing.T) {
 		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
 	}
 
-	path := filepath.Join(tempDir, "suggested_allowed.txt")
+	path := filepath.Join(tempDir, suggestionStoreFileName)
 	content, err := os.ReadFile(path)
 	if err != nil {
 		t.Fatalf("expected suggestion file to be written: %v", err)
@@ -194,6 +194,36 @@ func TestSearchHandlerReturnsJsonResults(t *testing.T) {
 	}
 }
 
+func TestSearchHandlerWordModeFindsLaterWordsAfterNamePrefixes(t *testing.T) {
+	store = newFoodStore("../../data")
+	if err := store.processDirectory("../../data"); err != nil {
+		t.Fatalf("processDirectory returned error: %v", err)
+	}
+
+	response := httptest.NewRecorder()
+	searchHandler(response, httptest.NewRequest(http.MethodGet, "/search?key=milk&type=searchbyword", nil))
+	var result responseData
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatalf("expected JSON response: %v", err)
+	}
+	if len(result.NotAllowed) < 2 || result.NotAllowed[0] != "Milk" || !contains(result.NotAllowed, "Coconut Milk (with gums, emulsifiers, or additives)") || contains(result.NotAllowed, "Soymilk") {
+		t.Fatalf("expected Milk first and word matches after it, got %#v", result.NotAllowed)
+	}
+	if !contains(result.Allowed, "Coconut Milk (without gums, emulsifiers, or additives)") {
+		t.Fatalf("expected allowed coconut milk word match, got %#v", result.Allowed)
+	}
+
+	response = httptest.NewRecorder()
+	searchHandler(response, httptest.NewRequest(http.MethodGet, "/search?key=milk&type=searchbysubstring", nil))
+	result = responseData{}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatalf("expected JSON response: %v", err)
+	}
+	if result.NotAllowed[len(result.NotAllowed)-1] != "Soymilk" {
+		t.Fatalf("expected substring match ranked last, got %#v", result.NotAllowed)
+	}
+}
+
 func TestHealthRouteDoesNotAnswerProbePaths(t *testing.T) {
 	mux := http.NewServeMux()
 	registerHandlers(mux)
@@ -268,7 +298,7 @@ func TestAdminReloadHandlerReloadsCatalogFromDisk(t *testing.T) {
 	}
 
 	reloadedStore := getStore()
-	searchResult := reloadedStore.match("Banana", "searchbytext")
+	searchResult := reloadedStore.match("Banana", "searchbytext", "en")
 	if !contains(searchResult.Allowed, "Banana") {
 		t.Fatalf("expected Banana after reload, got %#v", searchResult.Allowed)
 	}
@@ -305,7 +335,7 @@ func TestAdminReloadHandlerKeepsExistingCatalogOnReloadFailure(t *testing.T) {
 	}
 
 	currentStore := getStore()
-	searchResult := currentStore.match("Apple", "searchbytext")
+	searchResult := currentStore.match("Apple", "searchbytext", "en")
 	if !contains(searchResult.Allowed, "Apple") {
 		t.Fatalf("expected existing Apple catalog to remain loaded, got %#v", searchResult.Allowed)
 	}
@@ -317,7 +347,7 @@ func TestSoundSearchPorkDoesNotReturnUnrelatedCatalogItems(t *testing.T) {
 		t.Fatalf("processDirectory returned error: %v", err)
 	}
 
-	result := testStore.match("pork", "searchbysound")
+	result := testStore.match("pork", "searchbysound", "en")
 
 	if !contains(result.Allowed, "Pork") {
 		t.Fatalf("expected Pork in sound search results, got allowed=%#v notAllowed=%#v", result.Allowed, result.NotAllowed)
@@ -340,7 +370,7 @@ func TestSoundSearchHelpsWithPorkMisspelling(t *testing.T) {
 		t.Fatalf("processDirectory returned error: %v", err)
 	}
 
-	result := testStore.match("porc", "searchbysound")
+	result := testStore.match("porc", "searchbysound", "en")
 
 	if !contains(result.Allowed, "Pork") {
 		t.Fatalf("expected Pork for porc sound search, got allowed=%#v notAllowed=%#v", result.Allowed, result.NotAllowed)
@@ -383,7 +413,7 @@ func TestSoundSearchHelpsWithCommonCatalogMisspellings(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := testStore.match(tt.query, "searchbysound")
+			result := testStore.match(tt.query, "searchbysound", "en")
 			results := result.NotAllowed
 			if tt.allowed {
 				results = result.Allowed
@@ -404,7 +434,7 @@ func TestSoundSearchCumminFindsCuminPhrasesWithoutPumpkin(t *testing.T) {
 		t.Fatalf("processDirectory returned error: %v", err)
 	}
 
-	result := testStore.match("cummin", "searchbysound")
+	result := testStore.match("cummin", "searchbysound", "en")
 
 	for _, want := range []string{"Black Cumin", "Cumin Seed"} {
 		if !contains(result.NotAllowed, want) {
@@ -422,7 +452,7 @@ func TestCombinedSearchCumminFindsCuminPhrasesWithoutPumpkin(t *testing.T) {
 		t.Fatalf("processDirectory returned error: %v", err)
 	}
 
-	result := testStore.match("cummin", "")
+	result := testStore.match("cummin", "", "en")
 
 	for _, want := range []string{"Black Cumin", "Cumin Seed"} {
 		if !contains(result.NotAllowed, want) {
@@ -440,7 +470,7 @@ func TestCombinedSearchPorkKeepsTextMatchAndRejectsUnrelatedSoundMatches(t *test
 		t.Fatalf("processDirectory returned error: %v", err)
 	}
 
-	result := testStore.match("pork", "")
+	result := testStore.match("pork", "", "en")
 
 	if !contains(result.Allowed, "Pork") {
 		t.Fatalf("expected Pork in combined search results, got allowed=%#v notAllowed=%#v", result.Allowed, result.NotAllowed)
@@ -491,7 +521,7 @@ func TestSoundSearchMatchesAlternateMetaphoneKey(t *testing.T) {
 		alternateShortMetaphone: query.PrimaryShortKey(),
 	}
 
-	result := testStore.match("apple", "searchbysound")
+	result := testStore.match("apple", "searchbysound", "en")
 
 	if !contains(result.Allowed, "Applf") {
 		t.Fatalf("expected alternate metaphone key match, got %#v", result.Allowed)
@@ -508,7 +538,7 @@ func TestSoundSearchDoesNotMatchNearbyNumericMetaphoneKey(t *testing.T) {
 		alternateShortMetaphone: godoublemetaphone.METAPHONE_INVALID_KEY,
 	}
 
-	result := testStore.match("apple", "searchbysound")
+	result := testStore.match("apple", "searchbysound", "en")
 
 	if contains(result.Allowed, "Near Numeric Key") {
 		t.Fatalf("expected nearby numeric metaphone key not to match, got %#v", result.Allowed)
@@ -535,3 +565,39 @@ func contains(values []string, target string) bool {
 	}
 	return false
 }
+
+func TestSearchHandlerWritesSearchEvent(t *testing.T) {
+	tempDir := t.TempDir()
+	store = newFoodStore(tempDir)
+	store.searchLogPath = filepath.Join(tempDir, "search.log")
+	store.nameFoods["apples"] = &apiFood{allowed: true, name: "Apples"}
+
+	for _, key := range []string{"App", "Z%C3%A9zz"} {
+		request := httptest.NewRequest(http.MethodGet, "/search?key="+key+"&type=searchbytext", nil)
+		request.Header.Set("X-AIP-Client", "Android")
+		searchHandler(httptest.NewRecorder(), request)
+	}
+
+	content, err := os.ReadFile(store.searchLogPath)
+	if err != nil {
+		t.Fatalf("expected search log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two search events, got %q", string(content))
+	}
+
+	var hit, miss searchEvent
+	if err := json.Unmarshal([]byte(lines[0]), &hit); err != nil {
+		t.Fatalf("expected JSON search event: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &miss); err != nil {
+		t.Fatalf("expected JSON search event: %v", err)
+	}
+	if hit.Key != "app" || hit.Type != "searchbytext" || hit.Allowed != 1 || hit.Client != "android" || hit.Time.IsZero() {
+		t.Fatalf("unexpected hit event: %#v", hit)
+	}
+	if miss.Key != "zezz" || miss.Allowed != 0 || miss.NotAllowed != 0 {
+		t.Fatalf("unexpected zero-result event: %#v", miss)
+	}
+}
@@ -5,15 +5,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 )
 
-var logFileLock sync.Mutex
-
 type statusCaptureWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -111,15 +107,15 @@ func gatewaySecretMiddleware(config appConfig, next http.Handler) http.Handler {
 
 func requiresGatewaySecret(requestPath string) bool {
 	switch requestPath {
-	case "/search", "/suggest", "/feedback", "/categories", "/subcategory", adminReloadPath:
+	case "/search", "/suggest", "/feedback", "/categories", "/subcategory":
 		return true
 	default:
-		return false
+		return requiresAdminGatewaySecret(requestPath)
 	}
 }
 
 func requiresAdminGatewaySecret(requestPath string) bool {
-	return requestPath == adminReloadPath
+	return strings.HasPrefix(requestPath, adminPathPrefix)
 }
 
 func bodyLimitMiddleware(config appConfig, next http.Handler) http.Handler {
@@ -139,7 +135,7 @@ func recoverMiddleware(config appConfig, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if recovered := recover(); recovered != nil {
-				writeErrorLog(config.ErrorLogPath, fmt.Sprintf("panic path=%s error=%v", sanitizeLogValue(r.URL.RequestURI()), recovered))
+				writeErrorLog(config.ErrorLogPath, fmt.Sprintf("panic path=%s error=%v", sanitizeLogValue(logRequestURI(config.LogPrivacy, r)), recovered))
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 			}
 		}()
@@ -159,17 +155,18 @@ func accessLogMiddleware(config appConfig, next http.Handler) http.Handler {
 			statusCode = http.StatusOK
 		}
 
+		privacy := config.LogPrivacy
 		line := fmt.Sprintf(
 			"%s - - [%s] \"%s %s %s\" %d %d \"%s\" \"%s\" %dms",
-			sanitizeLogValue(remoteIP(r)),
+			sanitizeLogValue(logClientIP(privacy, remoteIP(r))),
 			time.Now().Format("02/Jan/2006:15:04:05 -0700"),
 			sanitizeLogValue(r.Method),
-			sanitizeLogValue(r.URL.RequestURI()),
+			sanitizeLogValue(logRequestURI(privacy, r)),
 			sanitizeLogValue(r.Proto),
 			statusCode,
 			capture.bytes,
 			sanitizeLogValue(r.Referer()),
-			sanitizeLogValue(r.UserAgent()),
+			sanitizeLogValue(logUserAgent(privacy, r)),
 			time.Since(start).Milliseconds(),
 		)
 		writeLogLine(config.AccessLogPath, line)
@@ -214,24 +211,11 @@ func writeLogLine(path string, line string) {
 		return
 	}
 
-	fullPath, err := filepath.Abs(path)
-	if err != nil {
-		return
-	}
-	if err = os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-		return
-	}
-
-	logFileLock.Lock()
-	defer logFileLock.Unlock()
-
-	file, err := os.OpenFile(fullPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	writer, err := logWriterFor(path)
 	if err != nil {
 		return
 	}
-	defer file.Close()
-
-	_, _ = fmt.Fprintln(file, line)
+	_ = writer.writeLine(line)
 }
 
 type fixedWindowRateLimiter struct {
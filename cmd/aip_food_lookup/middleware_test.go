@@ -60,6 +60,24 @@ func TestGatewaySecretMiddlewareProtectsAdminReloadWithoutPublicApiFlag(t *testi
 	}
 }
 
+func TestGatewaySecretMiddlewareProtectsEveryAdminPath(t *testing.T) {
+	config := appConfig{
+		GatewaySecretHeaderName: "X-Internal-Api-Key",
+		GatewaySecret:           "secret",
+	}
+	handler := gatewaySecretMiddleware(config, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, path := range []string{adminSuggestionsPath, adminSuggestionsAcceptPath, adminSuggestionsRejectPath, adminDisputesPath, adminDisputesAcceptPath, adminDisputesRejectPath, adminSinksPath, adminFeedbackPath, adminFeedbackHandlePath, adminSearchPath} {
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		if response.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401 for %s, got %d", path, response.Code)
+		}
+	}
+}
+
 func TestGatewaySecretMiddlewareRejectsAdminReloadWithoutConfiguredSecret(t *testing.T) {
 	handler := gatewaySecretMiddleware(appConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	slackOutboxFileName   = "slack-outbox.jsonl"
+	outboxIdlePoll        = time.Minute
+	outboxDeliveryTimeout = 5 * time.Second
+	outboxMinBackoff      = time.Second
+)
+
+// outboxMessage is one queued notification. Body is the JSON posted to the
+// outbox webhook; the URL itself is never written to disk.
+type outboxMessage struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Body        json.RawMessage `json:"body"`
+	Created     time.Time       `json:"created"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+}
+
+// notificationOutbox is a durable JSONL queue in front of one webhook. Sinks
+// enqueue and return immediately; run delivers in the background, retrying
+// failures with exponential backoff. Messages the webhook rejects outright, or
+// that fail maxAttempts times, move to the dead-letter file instead.
+type notificationOutbox struct {
+	mu          sync.Mutex
+	path        string
+	deadPath    string
+	maxAttempts int
+	webhookURL  string
+	client      *http.Client
+	errorLog    string
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	wake        chan struct{}
+	now         func() time.Time
+}
+
+// newSlackOutbox returns the Slack outbox, or nil when Slack is not
+// configured or the outbox is disabled and sinks should post directly.
+func newSlackOutbox(config appConfig) *notificationOutbox {
+	if !config.SlackOutbox.Enabled || strings.TrimSpace(config.SlackFeedbackWebhookURL) == "" {
+		return nil
+	}
+	path := config.SlackOutbox.Path
+	if strings.TrimSpace(path) == "" {
+		path = filepath.Join(config.DataFolder, slackOutboxFileName)
+	}
+	minBackoff := time.Duration(config.SlackOutbox.RetryMinSeconds) * time.Second
+	if minBackoff < outboxMinBackoff {
+		minBackoff = outboxMinBackoff
+	}
+	maxBackoff := time.Duration(config.SlackOutbox.RetryMaxSeconds) * time.Second
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
+	return &notificationOutbox{
+		path:        path,
+		deadPath:    deadLetterPath(path),
+		maxAttempts: config.SlackOutbox.MaxAttempts,
+		webhookURL:  config.SlackFeedbackWebhookURL,
+		client:      &http.Client{Timeout: outboxDeliveryTimeout},
+		errorLog:    config.ErrorLogPath,
+		minBackoff:  minBackoff,
+		maxBackoff:  maxBackoff,
+		wake:        make(chan struct{}, 1),
+		now:         time.Now,
+	}
+}
+
+// deadLetterPath names the file beside the outbox that keeps undeliverable
+// messages, slack-outbox.dead.jsonl for slack-outbox.jsonl.
+func deadLetterPath(path string) string {
+	extension := filepath.Ext(path)
+	return strings.TrimSuffix(path, extension) + ".dead" + extension
+}
+
+// enqueue durably appends one message and wakes the delivery worker.
+func (o *notificationOutbox) enqueue(kind string, body []byte) error {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	now := o.now().UTC()
+	line, err := json.Marshal(outboxMessage{ID: hex.EncodeToString(id), Kind: kind, Body: body, Created: now, NextAttempt: now})
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	err = appendOutboxLine(o.path, line)
+	o.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func appendOutboxLine(path string, line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// run delivers queued messages forever, sleeping until the next retry is due
+// or a new message arrives.
+func (o *notificationOutbox) run() {
+	for {
+		wait := outboxIdlePoll
+		if next, err := o.drain(); err != nil {
+			writeErrorLog(o.errorLog, fmt.Sprintf("notification outbox failed: %v", err))
+		} else if !next.IsZero() {
+			wait = next.Sub(o.now())
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-o.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// drain attempts every due message once, returning when the earliest
+// remaining message is next due, or the zero time when the queue is empty.
+// Delivery happens outside the lock, so handlers can enqueue meanwhile.
+func (o *notificationOutbox) drain() (time.Time, error) {
+	o.mu.Lock()
+	messages, err := readOutbox(o.path)
+	o.mu.Unlock()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now := o.now()
+	failures := make(map[string]error)
+	delivered := make(map[string]bool)
+	for _, message := range messages {
+		if message.NextAttempt.After(now) {
+			continue
+		}
+		if err := postSlack(o.client, o.webhookURL, message.Body); err != nil {
+			failures[message.ID] = err
+		} else {
+			delivered[message.ID] = true
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	current, err := readOutbox(o.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	remaining := make([]outboxMessage, 0, len(current))
+	var next time.Time
+	for _, message := range current {
+		if delivered[message.ID] {
+			continue
+		}
+		if failure, failed := failures[message.ID]; failed {
+			message.Attempts++
+			message.LastError = failure.Error()
+			message.NextAttempt = o.now().UTC().Add(o.backoff(message.Attempts))
+			writeErrorLog(o.errorLog, fmt.Sprintf("%s delivery failed (attempt %d): %v", message.Kind, message.Attempts, failure))
+			if o.undeliverable(message, failure) {
+				err := o.deadLetter(message)
+				if err == nil {
+					continue
+				}
+				writeErrorLog(o.errorLog, fmt.Sprintf("%s dead-letter write failed: %v", message.Kind, err))
+			}
+		}
+		if next.IsZero() || message.NextAttempt.Before(next) {
+			next = message.NextAttempt
+		}
+		remaining = append(remaining, message)
+	}
+	return next, writeOutbox(o.path, remaining)
+}
+
+// undeliverable reports whether a failed message should stop retrying: the
+// webhook rejected it as a client error, or it has used its attempts.
+func (o *notificationOutbox) undeliverable(message outboxMessage, failure error) bool {
+	var statusErr *webhookStatusError
+	if errors.As(failure, &statusErr) && statusErr.permanent() {
+		return true
+	}
+	return o.maxAttempts > 0 && message.Attempts >= o.maxAttempts
+}
+
+// deadLetter appends the message to the dead-letter file. The caller holds mu.
+func (o *notificationOutbox) deadLetter(message outboxMessage) error {
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if err := appendOutboxLine(o.deadPath, line); err != nil {
+		return err
+	}
+	writeErrorLog(o.errorLog, fmt.Sprintf("%s moved to %s after %d attempts: %s", message.Kind, o.deadPath, message.Attempts, message.LastError))
+	return nil
+}
+
+// backoff doubles from the minimum delay up to the maximum.
+func (o *notificationOutbox) backoff(attempts int) time.Duration {
+	delay := o.minBackoff
+	for i := 1; i < attempts && delay < o.maxBackoff; i++ {
+		delay *= 2
+	}
+	if o.maxBackoff > 0 && delay > o.maxBackoff {
+		return o.maxBackoff
+	}
+	return delay
+}
+
+// readOutbox loads queued messages, skipping lines that no longer decode.
+func readOutbox(path string) ([]outboxMessage, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var messages []outboxMessage
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var message outboxMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err == nil && message.ID != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages, scanner.Err()
+}
+
+func writeOutbox(path string, messages []outboxMessage) error {
+	var buffer bytes.Buffer
+	for _, message := range messages {
+		line, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		buffer.Write(append(line, '\n'))
+	}
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, buffer.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
+func slackPayload(text string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"text":   text,
+		"mrkdwn": true,
+	})
+}
+
+func postSlack(client *http.Client, webhookURL string, payload []byte) error {
+	httpRequest, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &webhookStatusError{Service: "slack", StatusCode: response.StatusCode}
+	}
+	return nil
+}
+
+// webhookStatusError is a non-2xx webhook response.
+type webhookStatusError struct {
+	Service    string
+	StatusCode int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.Service, e.StatusCode)
+}
+
+// permanent reports whether retrying cannot help: a 4xx other than a timeout
+// or rate limit means the webhook refuses this request as sent.
+func (e *webhookStatusError) permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 &&
+		e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+}
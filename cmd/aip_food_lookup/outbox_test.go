@@ -0,0 +1,195 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlackOutboxRetriesUntilDelivered(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		}
+	}))
+	defer slackServer.Close()
+
+	tempDir := t.TempDir()
+	config := appConfig{
+		DataFolder:              tempDir,
+		ErrorLogPath:            filepath.Join(tempDir, "errors.log"),
+		SlackFeedbackWebhookURL: slackServer.URL,
+		SlackOutbox:             slackOutboxConfig{Enabled: true, RetryMinSeconds: 10, RetryMaxSeconds: 15},
+	}
+	outbox := newSlackOutbox(config)
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	outbox.now = func() time.Time { return clock }
+
+	store = newFoodStore(tempDir)
+	store.feedbackSink = newFeedbackSink(config, outbox)
+	if err := store.submitFeedback(feedbackRequest{Name: "Joe", Message: "Queued?"}); err != nil {
+		t.Fatalf("submitFeedback: %v", err)
+	}
+	if len(bodies) != 0 {
+		t.Fatalf("expected the handler path not to call Slack, got %d requests", len(bodies))
+	}
+
+	next, err := outbox.drain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := clock.Add(10 * time.Second); !next.Equal(want) {
+		t.Fatalf("first retry at %v, want %v", next, want)
+	}
+	if next, _ := outbox.drain(); len(bodies) != 1 || !next.Equal(clock.Add(10*time.Second)) {
+		t.Fatalf("expected no retry before the backoff elapsed, got %d requests", len(bodies))
+	}
+
+	clock = clock.Add(10 * time.Second)
+	next, _ = outbox.drain()
+	if want := clock.Add(15 * time.Second); !next.Equal(want) {
+		t.Fatalf("second retry at %v, want capped %v", next, want)
+	}
+
+	clock = clock.Add(15 * time.Second)
+	if next, err := outbox.drain(); err != nil || !next.IsZero() {
+		t.Fatalf("expected an empty outbox, got next %v err %v", next, err)
+	}
+	if len(bodies) != 3 || !strings.Contains(bodies[2], "Queued?") {
+		t.Fatalf("unexpected Slack requests %#v", bodies)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, slackOutboxFileName))
+	if err != nil || len(content) != 0 {
+		t.Fatalf("expected drained outbox file, got %q err %v", content, err)
+	}
+	feedback, err := os.ReadFile(filepath.Join(tempDir, "feedback.jsonl"))
+	if err != nil || strings.Count(string(feedback), "Queued?") != 1 {
+		t.Fatalf("expected the feedback store to keep one copy alongside Slack, got %q err %v", feedback, err)
+	}
+	errorLog, _ := os.ReadFile(filepath.Join(tempDir, "errors.log"))
+	if !strings.Contains(string(errorLog), "slack feedback delivery failed (attempt 2)") {
+		t.Fatalf("expected retry failures in error log, got %q", errorLog)
+	}
+}
+
+func TestSlackOutboxWorkerDeliversQueuedSuggestions(t *testing.T) {
+	delivered := make(chan string, 4)
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		delivered <- string(body)
+	}))
+	defer slackServer.Close()
+
+	tempDir := t.TempDir()
+	config := appConfig{
+		DataFolder:              tempDir,
+		SlackFeedbackWebhookURL: slackServer.URL,
+		SlackOutbox:             slackOutboxConfig{Enabled: true, Path: filepath.Join(tempDir, "queue", "slack.jsonl")},
+	}
+	outbox := newSlackOutbox(config)
+	go outbox.run()
+
+	sink := newSuggestionSink(config, outbox)
+	if err := sink.submitSuggestion(requestData{InputText: "cassava chips", Allowed: true}); err != nil {
+		t.Fatalf("submitSuggestion: %v", err)
+	}
+
+	select {
+	case body := <-delivered:
+		if !strings.Contains(body, "cassava chips") {
+			t.Fatalf("unexpected Slack payload %q", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("outbox worker did not deliver the suggestion")
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		content, err := os.ReadFile(config.SlackOutbox.Path)
+		if _, tmpErr := os.Stat(config.SlackOutbox.Path + ".tmp"); err == nil && len(content) == 0 && os.IsNotExist(tmpErr) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox was not drained, content %q err %v", content, err)
+		}
+	}
+}
+
+func TestNewSlackOutboxDisabled(t *testing.T) {
+	if newSlackOutbox(appConfig{SlackFeedbackWebhookURL: "https://hooks.example"}) != nil {
+		t.Fatal("expected no outbox when disabled")
+	}
+	if newSlackOutbox(appConfig{SlackOutbox: slackOutboxConfig{Enabled: true}}) != nil {
+		t.Fatal("expected no outbox without a Slack webhook")
+	}
+}
+
+func TestSlackOutboxDeadLettersRejectedAndExhaustedMessages(t *testing.T) {
+	status := http.StatusBadRequest
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer slackServer.Close()
+
+	tempDir := t.TempDir()
+	config := appConfig{
+		DataFolder:              tempDir,
+		ErrorLogPath:            filepath.Join(tempDir, "errors.log"),
+		SlackFeedbackWebhookURL: slackServer.URL,
+		SlackOutbox:             slackOutboxConfig{Enabled: true, RetryMinSeconds: 1, RetryMaxSeconds: 1, MaxAttempts: 2},
+	}
+	outbox := newSlackOutbox(config)
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	outbox.now = func() time.Time { return clock }
+
+	if err := outbox.enqueue("slack feedback", []byte(`{"text":"rejected"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if next, err := outbox.drain(); err != nil || !next.IsZero() {
+		t.Fatalf("expected a 400 to leave the outbox empty, got next %v err %v", next, err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := outbox.enqueue("slack feedback", []byte(`{"text":"exhausted"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if next, _ := outbox.drain(); next.IsZero() {
+		t.Fatal("expected a 503 to be retried")
+	}
+	clock = clock.Add(time.Second)
+	if next, err := outbox.drain(); err != nil || !next.IsZero() {
+		t.Fatalf("expected the message to stop after 2 attempts, got next %v err %v", next, err)
+	}
+
+	dead, err := readOutbox(filepath.Join(tempDir, "slack-outbox.dead.jsonl"))
+	if err != nil || len(dead) != 2 {
+		t.Fatalf("expected two dead-lettered messages, got %#v err %v", dead, err)
+	}
+	if string(dead[0].Body) != `{"text":"rejected"}` || dead[0].Attempts != 1 || dead[0].LastError != "slack returned status 400" {
+		t.Fatalf("unexpected rejected message %#v", dead[0])
+	}
+	if string(dead[1].Body) != `{"text":"exhausted"}` || dead[1].Attempts != 2 {
+		t.Fatalf("unexpected exhausted message %#v", dead[1])
+	}
+}
+
+func TestNewSlackOutboxClampsBackoff(t *testing.T) {
+	outbox := newSlackOutbox(appConfig{
+		SlackFeedbackWebhookURL: "https://hooks.example",
+		SlackOutbox:             slackOutboxConfig{Enabled: true, RetryMinSeconds: 0, RetryMaxSeconds: -5},
+	})
+	if outbox.minBackoff != time.Second || outbox.maxBackoff != time.Second {
+		t.Fatalf("expected backoff clamped to 1s, got min %v max %v", outbox.minBackoff, outbox.maxBackoff)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+const (
+	searchTypeTextAndSound = "searchbytextandsound"
+	searchClientMaxLen     = 40
+)
+
+// searchEvent is one line of the JSONL search analytics log read by
+// search_coverage extract.
+type searchEvent struct {
+	Time       time.Time `json:"time"`
+	Key        string    `json:"key"`
+	Type       string    `json:"type"`
+	Allowed    int       `json:"allowed"`
+	NotAllowed int       `json:"notAllowed"`
+	LatencyMS  float64   `json:"latencyMs"`
+	Client     string    `json:"client"`
+}
+
+func newSearchEvent(r *http.Request, key string, typeSearch string, response responseData, start time.Time) searchEvent {
+	typeSearch = strings.ToLower(strings.TrimSpace(typeSearch))
+	if typeSearch == "" {
+		typeSearch = searchTypeTextAndSound
+	}
+
+	return searchEvent{
+		Time:       start.UTC(),
+		Key:        foodcatalog.Fold(key),
+		Type:       typeSearch,
+		Allowed:    len(response.Allowed),
+		NotAllowed: len(response.NotAllowed),
+		LatencyMS:  float64(time.Since(start).Microseconds()) / 1000,
+		Client:     searchClient(r),
+	}
+}
+
+// searchClient reads the app platform from X-AIP-Client, keeping only simple
+// identifier characters and a bounded length so the analytics log stays
+// safe to export as TSV.
+func searchClient(r *http.Request) string {
+	client := strings.Map(func(char rune) rune {
+		switch {
+		case char >= 'a' && char <= 'z', char >= '0' && char <= '9', char == '.', char == '-', char == '_', char == '/':
+			return char
+		default:
+			return -1
+		}
+	}, strings.ToLower(r.Header.Get("X-AIP-Client")))
+	if len(client) > searchClientMaxLen {
+		client = client[:searchClientMaxLen]
+	}
+	if client == "" {
+		return "-"
+	}
+	return client
+}
+
+func writeSearchEvent(path string, event searchEvent) {
+	if strings.TrimSpace(path) == "" {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	writeLogLine(path, string(payload))
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sinkChainFeedback   = "feedback"
+	sinkChainSuggestion = "suggestion"
+
+	// sinkModeAll delivers to every sink.
+	sinkModeAll = "all"
+	// sinkModeFirstSuccess tries sinks in order and stops at the first that
+	// delivers.
+	sinkModeFirstSuccess = "first-success"
+	// sinkModeFallback delivers to every sink but the last, which only
+	// receives events that one of the others failed to deliver.
+	sinkModeFallback = "fallback"
+
+	// sinkNameFile is still accepted in sink lists, but feedback and
+	// suggestions are stored before any chain runs.
+	sinkNameFile    = "file"
+	sinkNameSlack   = "slack"
+	sinkNameWebhook = "webhook"
+	sinkNameSMTP    = "smtp"
+)
+
+// sinkChain fans feedback or suggestions out to several sinks. In every mode
+// an event counts as delivered when at least one sink accepted it; each
+// failing sink is logged on its own and counted in its stats.
+type sinkChain struct {
+	kind     string
+	mode     string
+	members  []*sinkMember
+	errorLog string
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+type sinkMember struct {
+	name       string
+	feedback   feedbackSink
+	suggestion suggestionSink
+	stats      sinkStats
+}
+
+// sinkStats counts deliveries for one sink in one chain, for /admin/sinks.
+type sinkStats struct {
+	Chain       string     `json:"chain"`
+	Sink        string     `json:"sink"`
+	Attempts    int64      `json:"attempts"`
+	Successes   int64      `json:"successes"`
+	Failures    int64      `json:"failures"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+}
+
+// newSinkChain builds the feedback or suggestion notification chain from
+// config. Without configured sinks, both go to every configured remote sink.
+// Feedback and suggestions are always stored before the chain runs, so the
+// file sink older configs list is skipped. Unknown or unconfigured sinks are
+// logged and skipped.
+func newSinkChain(config appConfig, outbox *notificationOutbox, kind string) *sinkChain {
+	names, mode := config.Sinks.Suggestions, config.Sinks.SuggestionsMode
+	if kind == sinkChainFeedback {
+		names, mode = config.Sinks.Feedback, config.Sinks.FeedbackMode
+	}
+	if len(names) == 0 {
+		names, mode = defaultSinkNames(config, kind)
+	}
+	switch mode {
+	case sinkModeAll, sinkModeFirstSuccess, sinkModeFallback:
+	case "":
+		mode = sinkModeAll
+	default:
+		writeErrorLog(config.ErrorLogPath, fmt.Sprintf("unknown %s sink mode %q, using %s", kind, mode, sinkModeAll))
+		mode = sinkModeAll
+	}
+
+	chain := &sinkChain{kind: kind, mode: mode, errorLog: config.ErrorLogPath, now: time.Now}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == sinkNameFile {
+			continue
+		}
+		member, err := newSinkMember(config, outbox, kind, name)
+		if err != nil {
+			writeErrorLog(config.ErrorLogPath, fmt.Sprintf("%s sink %q skipped: %v", kind, name, err))
+			continue
+		}
+		member.stats = sinkStats{Chain: kind, Sink: name}
+		chain.members = append(chain.members, member)
+	}
+	return chain
+}
+
+func defaultSinkNames(config appConfig, kind string) ([]string, string) {
+	var remote []string
+	if strings.TrimSpace(config.Webhook.URL) != "" {
+		remote = append(remote, sinkNameWebhook)
+	}
+	if kind == sinkChainFeedback && newSMTPFeedbackSink(config) != nil {
+		remote = append(remote, sinkNameSMTP)
+	}
+	if strings.TrimSpace(config.SlackFeedbackWebhookURL) != "" {
+		remote = append(remote, sinkNameSlack)
+	}
+	return remote, sinkModeAll
+}
+
+func newSinkMember(config appConfig, outbox *notificationOutbox, kind string, name string) (*sinkMember, error) {
+	member := &sinkMember{name: name}
+	switch name {
+	case sinkNameSlack:
+		slack := newSlackSink(config, outbox)
+		if slack == nil {
+			return nil, errors.New("slack webhook URL is not configured")
+		}
+		member.feedback, member.suggestion = slack, slack
+	case sinkNameWebhook:
+		webhook := newWebhookSink(config)
+		if webhook == nil && strings.TrimSpace(config.Webhook.URL) != "" {
+			return nil, errors.New("webhook secret is not configured; unsigned requests are refused")
+		}
+		if webhook == nil {
+			return nil, errors.New("webhook URL is not configured")
+		}
+		member.feedback, member.suggestion = webhook, webhook
+	case sinkNameSMTP:
+		if kind != sinkChainFeedback {
+			return nil, errors.New("smtp only delivers feedback")
+		}
+		email := newSMTPFeedbackSink(config)
+		if email == nil {
+			return nil, errors.New("smtp host, from and to are not configured")
+		}
+		member.feedback = email
+	default:
+		return nil, errors.New("unknown sink")
+	}
+	return member, nil
+}
+
+func (c *sinkChain) submitFeedback(request feedbackRequest) error {
+	return c.deliver(func(member *sinkMember) error {
+		return member.feedback.submitFeedback(request)
+	})
+}
+
+func (c *sinkChain) submitSuggestion(request requestData) error {
+	return c.deliver(func(member *sinkMember) error {
+		return member.suggestion.submitSuggestion(request)
+	})
+}
+
+func (c *sinkChain) deliver(send func(*sinkMember) error) error {
+	if len(c.members) == 0 {
+		return fmt.Errorf("no %s sinks configured", c.kind)
+	}
+	primary, fallback := c.members, []*sinkMember(nil)
+	if c.mode == sinkModeFallback && len(c.members) > 1 {
+		primary, fallback = c.members[:len(c.members)-1], c.members[len(c.members)-1:]
+	}
+
+	var errs []error
+	delivered := false
+	for _, member := range primary {
+		if err := c.attempt(member, send); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delivered = true
+		if c.mode == sinkModeFirstSuccess {
+			return nil
+		}
+	}
+	if len(errs) > 0 {
+		for _, member := range fallback {
+			if err := c.attempt(member, send); err != nil {
+				errs = append(errs, err)
+			} else {
+				delivered = true
+			}
+		}
+	}
+	if delivered {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+func (c *sinkChain) attempt(member *sinkMember, send func(*sinkMember) error) error {
+	err := send(member)
+
+	c.mu.Lock()
+	member.stats.Attempts++
+	if err == nil {
+		member.stats.Successes++
+	} else {
+		failedAt := c.now().UTC()
+		member.stats.Failures++
+		member.stats.LastError = err.Error()
+		member.stats.LastFailure = &failedAt
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		writeErrorLog(c.errorLog, fmt.Sprintf("%s %s failed: %v", member.name, c.kind, err))
+	}
+	return err
+}
+
+// stats returns a snapshot of every member's counters in chain order.
+func (c *sinkChain) stats() []sinkStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([]sinkStats, 0, len(c.members))
+	for _, member := range c.members {
+		result = append(result, member.stats)
+	}
+	return result
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingSink counts deliveries and fails when err is set.
+type recordingSink struct {
+	calls int
+	err   error
+}
+
+func (s *recordingSink) submitFeedback(feedbackRequest) error {
+	s.calls++
+	return s.err
+}
+
+func testSinkChain(mode string, sinks ...*recordingSink) *sinkChain {
+	chain := &sinkChain{kind: sinkChainFeedback, mode: mode, now: time.Now}
+	for index, sink := range sinks {
+		name := string(rune('a' + index))
+		chain.members = append(chain.members, &sinkMember{name: name, feedback: sink, stats: sinkStats{Chain: sinkChainFeedback, Sink: name}})
+	}
+	return chain
+}
+
+func TestSinkChainModes(t *testing.T) {
+	down := errors.New("down")
+	tests := []struct {
+		mode    string
+		errs    []error
+		calls   []int
+		wantErr bool
+	}{
+		{sinkModeAll, []error{nil, down, nil}, []int{1, 1, 1}, false},
+		{sinkModeAll, []error{down, down}, []int{1, 1}, true},
+		{sinkModeFirstSuccess, []error{down, nil, nil}, []int{1, 1, 0}, false},
+		{sinkModeFallback, []error{nil, nil, nil}, []int{1, 1, 0}, false},
+		{sinkModeFallback, []error{nil, down, nil}, []int{1, 1, 1}, false},
+		{sinkModeFallback, []error{down, down}, []int{1, 1}, true},
+	}
+	for _, test := range tests {
+		var sinks []*recordingSink
+		for _, err := range test.errs {
+			sinks = append(sinks, &recordingSink{err: err})
+		}
+		err := testSinkChain(test.mode, sinks...).submitFeedback(feedbackRequest{Message: "hi"})
+		if (err != nil) != test.wantErr {
+			t.Fatalf("%s %v: err = %v", test.mode, test.errs, err)
+		}
+		for index, sink := range sinks {
+			if sink.calls != test.calls[index] {
+				t.Fatalf("%s %v: sink %d called %d times, want %d", test.mode, test.errs, index, sink.calls, test.calls[index])
+			}
+		}
+	}
+}
+
+func TestConfiguredSinkChainFansOutAndReportsStats(t *testing.T) {
+	slackHits, webhookHits := 0, 0
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slackHits++
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer slackServer.Close()
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhookHits++
+	}))
+	defer webhookServer.Close()
+
+	tempDir := t.TempDir()
+	config := appConfig{
+		DataFolder:              tempDir,
+		ErrorLogPath:            filepath.Join(tempDir, "errors.log"),
+		SlackFeedbackWebhookURL: slackServer.URL,
+		Webhook:                 webhookConfig{URL: webhookServer.URL, Secret: "s3cret", TimeoutSeconds: 5},
+		Sinks: sinkChainConfig{
+			Feedback:     []string{"Slack", "webhook", "smtp", "pager"},
+			FeedbackMode: sinkModeAll,
+		},
+	}
+	store = newFoodStore(tempDir)
+	store.feedbackSink = newFeedbackSink(config, nil)
+	store.suggestionSink = newSuggestionSink(config, nil)
+
+	if err := store.submitFeedback(feedbackRequest{Message: "Works?"}); err != nil {
+		t.Fatalf("submitFeedback: %v", err)
+	}
+	if slackHits != 1 || webhookHits != 1 {
+		t.Fatalf("slack hits %d, webhook hits %d", slackHits, webhookHits)
+	}
+	content, err := os.ReadFile(filepath.Join(tempDir, "feedback.jsonl"))
+	if err != nil || !strings.Contains(string(content), `"message":"Works?"`) {
+		t.Fatalf("expected feedback file, got %q err %v", content, err)
+	}
+	errorLog, _ := os.ReadFile(filepath.Join(tempDir, "errors.log"))
+	for _, expected := range []string{`feedback sink "smtp" skipped`, `feedback sink "pager" skipped: unknown sink`, "slack feedback failed: slack returned status 500"} {
+		if !strings.Contains(string(errorLog), expected) {
+			t.Fatalf("expected %q in error log %q", expected, errorLog)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	adminSinksHandler(response, httptest.NewRequest(http.MethodGet, adminSinksPath, nil))
+	var payload adminSinkListResponse
+	if err := json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Sinks) != 4 {
+		t.Fatalf("expected two feedback and two default suggestion sinks, got %#v", payload.Sinks)
+	}
+	slack := payload.Sinks[0]
+	if slack.Chain != sinkChainFeedback || slack.Sink != "slack" || slack.Attempts != 1 || slack.Failures != 1 || slack.LastFailure == nil || !strings.Contains(slack.LastError, "500") {
+		t.Fatalf("unexpected slack stats %#v", slack)
+	}
+	if webhook := payload.Sinks[1]; webhook.Successes != 1 || webhook.Failures != 0 {
+		t.Fatalf("unexpected webhook stats %#v", webhook)
+	}
+	if suggestion := payload.Sinks[2]; suggestion.Chain != sinkChainSuggestion || suggestion.Sink != "webhook" || suggestion.Attempts != 0 {
+		t.Fatalf("unexpected suggestion stats %#v", suggestion)
+	}
+}
+
+func TestDefaultSinkChainsNotifyEveryConfiguredSink(t *testing.T) {
+	config := appConfig{
+		SlackFeedbackWebhookURL: "https://hooks.example/slack",
+		Webhook:                 webhookConfig{URL: "https://hooks.example/webhook", Secret: "s3cret"},
+	}
+	for kind, want := range map[string]string{sinkChainFeedback: "webhook,slack", sinkChainSuggestion: "webhook,slack"} {
+		names, mode := defaultSinkNames(config, kind)
+		if strings.Join(names, ",") != want || mode != sinkModeAll {
+			t.Fatalf("%s default chain = %v in %s mode, want %s in all mode", kind, names, mode, want)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+const (
+	spamActionReject     = "reject"
+	spamActionQuarantine = "quarantine"
+
+	quarantineFileName = "quarantine.jsonl"
+
+	// duplicateKeyLimit caps each generation of remembered duplicate keys.
+	duplicateKeyLimit = 10000
+)
+
+// submission is one /feedback or /suggest request as seen by the content
+// filter. Text holds the user-entered fields checked for links and blocked
+// terms, and DuplicateKey identifies repeats; an empty key is never a
+// duplicate.
+type submission struct {
+	Kind         string
+	ClientIP     string
+	Honeypot     string
+	Text         []string
+	DuplicateKey string
+	Payload      any
+}
+
+// contentRule flags a submission as spam by returning a non-empty reason.
+// New checks plug into the filter by implementing it.
+type contentRule interface {
+	evaluate(submission) string
+}
+
+// contentRecorder is a contentRule that remembers submissions, such as the
+// duplicate check. record runs only for submissions that passed every rule,
+// so flagged spam never poisons later checks, and under the same lock as the
+// checks, so two concurrent repeats cannot both pass.
+type contentRecorder interface {
+	record(submission)
+}
+
+// contentFilter screens submissions before they reach the sinks. Flagged
+// submissions are either rejected with 400 or quarantined: written to the
+// quarantine file and answered as if they had been accepted, so spammers
+// learn nothing.
+type contentFilter struct {
+	action         string
+	honeypotField  string
+	quarantinePath string
+	privacy        logPrivacyConfig
+	errorLog       string
+	rules          []contentRule
+	screen         sync.Mutex
+	mu             sync.Mutex
+	now            func() time.Time
+}
+
+type quarantineRecord struct {
+	ReceivedAt time.Time `json:"receivedAt"`
+	Kind       string    `json:"kind"`
+	Reason     string    `json:"reason"`
+	Client     string    `json:"client"`
+	Payload    any       `json:"payload"`
+}
+
+// newContentFilter returns nil when filtering is disabled. A blocklist that
+// cannot be read is logged and skipped so the API still starts.
+func newContentFilter(config appConfig) *contentFilter {
+	settings := config.SpamFilter
+	if !settings.Enabled {
+		return nil
+	}
+
+	filter := &contentFilter{
+		action:         settings.Action,
+		honeypotField:  strings.TrimSpace(settings.HoneypotField),
+		quarantinePath: settings.QuarantinePath,
+		privacy:        config.LogPrivacy,
+		errorLog:       config.ErrorLogPath,
+		now:            time.Now,
+	}
+	if filter.action != spamActionReject {
+		filter.action = spamActionQuarantine
+	}
+	if strings.TrimSpace(filter.quarantinePath) == "" {
+		filter.quarantinePath = filepath.Join(config.DataFolder, quarantineFileName)
+	}
+
+	if filter.honeypotField != "" {
+		filter.rules = append(filter.rules, honeypotRule{})
+	}
+	if settings.MaxLinks >= 0 {
+		filter.rules = append(filter.rules, linkLimitRule{max: settings.MaxLinks})
+	}
+	if settings.DuplicateWindowSeconds > 0 {
+		filter.rules = append(filter.rules, newDuplicateRule(time.Duration(settings.DuplicateWindowSeconds)*time.Second))
+	}
+	if strings.TrimSpace(settings.BlocklistPath) != "" {
+		terms, err := loadBlocklist(settings.BlocklistPath)
+		if err != nil {
+			writeErrorLog(config.ErrorLogPath, fmt.Sprintf("spam blocklist skipped: %v", err))
+		} else {
+			filter.rules = append(filter.rules, blocklistRule{terms: terms})
+		}
+	}
+	return filter
+}
+
+// decodeSubmission decodes a JSON body into target and returns the value of
+// the filter's honeypot field, which real clients never send.
+func (f *contentFilter) decodeSubmission(r *http.Request, target any) (string, error) {
+	if f == nil || f.honeypotField == "" {
+		return "", json.NewDecoder(r.Body).Decode(target)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return "", err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return "", err
+	}
+	if value, ok := fields[f.honeypotField]; ok && value != nil && value != "" {
+		return fmt.Sprint(value), nil
+	}
+	return "", nil
+}
+
+// intercept reports whether the submission was flagged and, if so, has
+// already written the response.
+func (f *contentFilter) intercept(w http.ResponseWriter, item submission) bool {
+	if f == nil {
+		return false
+	}
+	if reason := f.screenSubmission(item); reason != "" {
+		f.flag(w, item, reason)
+		return true
+	}
+	return false
+}
+
+// screenSubmission runs every rule and, when none flags the submission,
+// records it, all under f.screen.
+func (f *contentFilter) screenSubmission(item submission) string {
+	f.screen.Lock()
+	defer f.screen.Unlock()
+	for _, rule := range f.rules {
+		if reason := rule.evaluate(item); reason != "" {
+			return reason
+		}
+	}
+	for _, rule := range f.rules {
+		if recorder, ok := rule.(contentRecorder); ok {
+			recorder.record(item)
+		}
+	}
+	return ""
+}
+
+// flag rejects or quarantines a flagged submission and writes the response.
+func (f *contentFilter) flag(w http.ResponseWriter, item submission, reason string) {
+	if f.action == spamActionReject {
+		http.Error(w, "Submission rejected", http.StatusBadRequest)
+		return
+	}
+	if err := f.quarantine(item, reason); err != nil {
+		writeErrorLog(f.errorLog, fmt.Sprintf("%s quarantine failed: %v", item.Kind, err))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *contentFilter) quarantine(item submission, reason string) error {
+	line, err := json.Marshal(quarantineRecord{
+		ReceivedAt: f.now().UTC(),
+		Kind:       item.Kind,
+		Reason:     reason,
+		Client:     logClientIP(f.privacy, item.ClientIP),
+		Payload:    item.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(f.quarantinePath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.quarantinePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+type honeypotRule struct{}
+
+func (honeypotRule) evaluate(item submission) string {
+	if strings.TrimSpace(item.Honeypot) != "" {
+		return "honeypot"
+	}
+	return ""
+}
+
+type linkLimitRule struct {
+	max int
+}
+
+func (r linkLimitRule) evaluate(item submission) string {
+	links := 0
+	for _, text := range item.Text {
+		lower := strings.ToLower(text)
+		links += strings.Count(lower, "http://") + strings.Count(lower, "https://")
+		links += strings.Count(lower, "www.") - strings.Count(lower, "://www.")
+	}
+	if links > r.max {
+		return fmt.Sprintf("%d links", links)
+	}
+	return ""
+}
+
+// duplicateRule remembers hashed duplicate keys for the window in two
+// generations. Keys go into current, which becomes previous once it is a
+// window old or holds duplicateKeyLimit keys, so memory stays bounded and
+// expiry costs nothing per request. Under a flood of distinct keys a repeat
+// can be forgotten early, which only lets a duplicate through.
+type duplicateRule struct {
+	window time.Duration
+	keys   *duplicateKeys
+	now    func() time.Time
+}
+
+type duplicateKeys struct {
+	mu       sync.Mutex
+	started  time.Time
+	current  map[string]time.Time
+	previous map[string]time.Time
+}
+
+func newDuplicateRule(window time.Duration) duplicateRule {
+	return duplicateRule{window: window, keys: &duplicateKeys{current: make(map[string]time.Time)}, now: time.Now}
+}
+
+func (r duplicateRule) evaluate(item submission) string {
+	if item.DuplicateKey == "" {
+		return ""
+	}
+	key := duplicateHash(item)
+	now := r.now()
+
+	r.keys.mu.Lock()
+	defer r.keys.mu.Unlock()
+	r.rotate(now)
+	for _, generation := range []map[string]time.Time{r.keys.current, r.keys.previous} {
+		if seenAt, exists := generation[key]; exists && now.Sub(seenAt) < r.window {
+			return "duplicate"
+		}
+	}
+	return ""
+}
+
+func (r duplicateRule) record(item submission) {
+	if item.DuplicateKey == "" {
+		return
+	}
+	key := duplicateHash(item)
+	now := r.now()
+
+	r.keys.mu.Lock()
+	defer r.keys.mu.Unlock()
+	r.rotate(now)
+	r.keys.current[key] = now
+}
+
+// rotate starts a new generation when the current one is a window old or
+// full. The caller holds keys.mu.
+func (r duplicateRule) rotate(now time.Time) {
+	if now.Sub(r.keys.started) < r.window && len(r.keys.current) < duplicateKeyLimit {
+		return
+	}
+	r.keys.previous = r.keys.current
+	r.keys.current = make(map[string]time.Time)
+	r.keys.started = now
+}
+
+func duplicateHash(item submission) string {
+	sum := sha256.Sum256([]byte(item.Kind + "\x00" + item.DuplicateKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// blocklistRule matches whole words or phrases, ignoring case and
+// punctuation.
+type blocklistRule struct {
+	terms []string
+}
+
+func (r blocklistRule) evaluate(item submission) string {
+	text := " " + blocklistWords(strings.Join(item.Text, " ")) + " "
+	for _, term := range r.terms {
+		if strings.Contains(text, " "+term+" ") {
+			return "blocklist"
+		}
+	}
+	return ""
+}
+
+// loadBlocklist reads one term per line, skipping blanks and # comments.
+func loadBlocklist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if term := blocklistWords(line); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(terms) == 0 {
+		return nil, errors.New("blocklist " + path + " has no terms")
+	}
+	return terms, nil
+}
+
+func blocklistWords(text string) string {
+	return strings.Join(strings.FieldsFunc(foodcatalog.Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// duplicateText folds case, accents and whitespace so trivially varied
+// repeats match.
+func duplicateText(values ...string) string {
+	return foodcatalog.Fold(strings.Join(values, "\n"))
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newSpamTestStore(t *testing.T, settings spamFilterConfig) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	store = newFoodStore(tempDir)
+	store.contentFilter = newContentFilter(appConfig{
+		DataFolder:   tempDir,
+		ErrorLogPath: filepath.Join(tempDir, "errors.log"),
+		LogPrivacy:   logPrivacyConfig{IPMode: logIPModeTruncate},
+		SpamFilter:   settings,
+	})
+	return tempDir
+}
+
+func postSpamTest(handler http.HandlerFunc, path string, body string) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	handler(response, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return response
+}
+
+func TestContentFilterRejectsHoneypotAndTooManyLinks(t *testing.T) {
+	tempDir := newSpamTestStore(t, spamFilterConfig{Enabled: true, Action: spamActionReject, HoneypotField: "website", MaxLinks: 1})
+
+	if response := postSpamTest(feedbackHandler, "/feedback", `{"name":"Bot","message":"Hello","website":"http://spam.example"}`); response.Code != http.StatusBadRequest {
+		t.Fatalf("honeypot feedback status = %d", response.Code)
+	}
+	links := `{"name":"Bot","message":"see https://a.example and www.b.example"}`
+	if response := postSpamTest(feedbackHandler, "/feedback", links); response.Code != http.StatusBadRequest {
+		t.Fatalf("two-link feedback status = %d", response.Code)
+	}
+	if response := postSpamTest(feedbackHandler, "/feedback", `{"name":"Jo","message":"Recipe at https://www.example.com please","website":""}`); response.Code != http.StatusOK {
+		t.Fatalf("one-link feedback status = %d: %s", response.Code, response.Body.String())
+	}
+
+	content, _ := os.ReadFile(filepath.Join(tempDir, "feedback.jsonl"))
+	if lines := strings.Count(string(content), "\n"); lines != 1 || !strings.Contains(string(content), "Recipe") {
+		t.Fatalf("expected only the legitimate feedback, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, quarantineFileName)); !os.IsNotExist(err) {
+		t.Fatalf("reject mode should not quarantine, stat err %v", err)
+	}
+}
+
+func TestContentFilterQuarantinesDuplicatesAndBlockedTerms(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(blocklist, []byte("# terms\ncheap pills\n\ncasino\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tempDir := newSpamTestStore(t, spamFilterConfig{
+		Enabled:                true,
+		Action:                 spamActionQuarantine,
+		MaxLinks:               -1,
+		DuplicateWindowSeconds: 60,
+		BlocklistPath:          blocklist,
+	})
+
+	for _, body := range []string{
+		`{"inputText":"cassava chips","allowed":true}`,
+		`{"inputText":"Cassava  Chips","allowed":true}`,
+		`{"inputText":"cassava chips","allowed":false}`,
+		`{"inputText":"casino chips","allowed":true}`,
+		`{"inputText":"ghee","allowed":true,"note":"Buy CHEAP, pills!"}`,
+		`{"inputText":"casinos","allowed":true}`,
+	} {
+		if response := postSpamTest(suggestHandler, "/suggest", body); response.Code != http.StatusOK {
+			t.Fatalf("%s: status %d, want quarantined or accepted 200", body, response.Code)
+		}
+	}
+
+	suggestions, err := store.suggestions.list(suggestionStateAll, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 2 || suggestions[0].Text != "cassava chips" || suggestions[0].Count != 2 || suggestions[1].Text != "casinos" {
+		t.Fatalf("unexpected stored suggestions %#v", suggestions)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, quarantineFileName))
+	if err != nil {
+		t.Fatalf("expected quarantine file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected three quarantined suggestions, got %q", content)
+	}
+	var record quarantineRecord
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Kind != "suggestion" || record.Reason != "duplicate" || record.Client != "192.0.2.0" || record.ReceivedAt.IsZero() {
+		t.Fatalf("unexpected quarantine record %#v", record)
+	}
+	if !strings.Contains(lines[1], `"reason":"blocklist"`) || !strings.Contains(lines[2], `"note":"Buy CHEAP, pills!"`) {
+		t.Fatalf("unexpected quarantine lines %q", lines)
+	}
+}
+
+func TestContentFilterDisabledByDefault(t *testing.T) {
+	if filter := newContentFilter(loadConfig()); filter != nil {
+		t.Fatalf("expected no filter by default, got %#v", filter)
+	}
+}
+
+func TestDuplicateRuleRecordsOnlyAcceptedSubmissions(t *testing.T) {
+	filter := &contentFilter{
+		action: spamActionReject,
+		rules:  []contentRule{newDuplicateRule(time.Minute), blocklistRule{terms: []string{"casino"}}},
+	}
+	spam := submission{Kind: "suggestion", Text: []string{"plantain", "casino"}, DuplicateKey: "plantain"}
+	clean := submission{Kind: "suggestion", Text: []string{"plantain"}, DuplicateKey: "plantain"}
+
+	if !filter.intercept(httptest.NewRecorder(), spam) {
+		t.Fatal("expected the blocked submission to be flagged")
+	}
+	if filter.intercept(httptest.NewRecorder(), clean) {
+		t.Fatal("a flagged submission must not make the next one a duplicate")
+	}
+	if !filter.intercept(httptest.NewRecorder(), clean) {
+		t.Fatal("expected the repeat of an accepted submission to be a duplicate")
+	}
+}
+
+func TestDuplicateRuleBoundsAndExpiresKeys(t *testing.T) {
+	rule := newDuplicateRule(time.Minute)
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rule.now = func() time.Time { return clock }
+
+	first := submission{Kind: "feedback", DuplicateKey: "first"}
+	rule.record(first)
+	for i := 0; i <= duplicateKeyLimit; i++ {
+		rule.record(submission{Kind: "feedback", DuplicateKey: fmt.Sprint("key ", i)})
+	}
+	if len(rule.keys.current) > duplicateKeyLimit || len(rule.keys.previous) != duplicateKeyLimit {
+		t.Fatalf("expected generations capped at %d, got current %d previous %d", duplicateKeyLimit, len(rule.keys.current), len(rule.keys.previous))
+	}
+	if rule.evaluate(first) != "duplicate" {
+		t.Fatal("expected a key in the previous generation to still count")
+	}
+
+	clock = clock.Add(time.Minute)
+	if rule.evaluate(first) != "" {
+		t.Fatal("expected the key to expire after the window")
+	}
+	if len(rule.keys.current) != 0 {
+		t.Fatalf("expected a fresh generation after the window, got %d keys", len(rule.keys.current))
+	}
+}
+
+func TestDuplicateRuleLetsOneOfConcurrentRepeatsThrough(t *testing.T) {
+	filter := &contentFilter{action: spamActionReject, rules: []contentRule{newDuplicateRule(time.Minute)}}
+	item := submission{Kind: "feedback", DuplicateKey: duplicateText("192.0.2.1", "same message")}
+
+	var wait sync.WaitGroup
+	var accepted atomic.Int32
+	for i := 0; i < 16; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if !filter.intercept(httptest.NewRecorder(), item) {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wait.Wait()
+	if accepted.Load() != 1 {
+		t.Fatalf("expected exactly one of the concurrent repeats accepted, got %d", accepted.Load())
+	}
+}
+
+func TestFeedbackDuplicatesAreScopedToTheClient(t *testing.T) {
+	tempDir := newSpamTestStore(t, spamFilterConfig{Enabled: true, Action: spamActionReject, MaxLinks: -1, DuplicateWindowSeconds: 60})
+
+	for index, client := range []string{"192.0.2.1:1234", "198.51.100.7:1234", "192.0.2.1:1234"} {
+		request := httptest.NewRequest(http.MethodPost, "/feedback", strings.NewReader(`{"name":"Jo","message":"Please add tigernut flour"}`))
+		request.RemoteAddr = client
+		response := httptest.NewRecorder()
+		feedbackHandler(response, request)
+		if want := []int{http.StatusOK, http.StatusOK, http.StatusBadRequest}[index]; response.Code != want {
+			t.Fatalf("feedback %d from %s: status %d, want %d", index, client, response.Code, want)
+		}
+	}
+
+	content, _ := os.ReadFile(filepath.Join(tempDir, "feedback.jsonl"))
+	if lines := strings.Count(string(content), "\n"); lines != 2 {
+		t.Fatalf("expected feedback from both clients, got %q", content)
+	}
+}
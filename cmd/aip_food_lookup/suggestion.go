@@ -1,68 +1,40 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"net/http"
 	"strings"
-	"time"
 )
 
-type slackSuggestionSink struct {
-	webhookURL string
-	client     *http.Client
+// newSuggestionSink builds the suggestion sink chain from config.
+func newSuggestionSink(config appConfig, outbox *notificationOutbox) suggestionSink {
+	return newSinkChain(config, outbox, sinkChainSuggestion)
 }
 
-func newSuggestionSink(config appConfig) suggestionSink {
-	if strings.TrimSpace(config.SlackFeedbackWebhookURL) == "" {
-		return nil
-	}
-
-	return slackSuggestionSink{
-		webhookURL: config.SlackFeedbackWebhookURL,
-		client: &http.Client{
-			Timeout: 5 * time.Second,
-		},
-	}
+func (s *slackSink) submitSuggestion(request requestData) error {
+	return s.post("slack suggestion", buildSuggestionSlackMessage(request))
 }
 
-func (s slackSuggestionSink) submitSuggestion(request requestData) error {
-	payload, err := json.Marshal(map[string]any{
-		"text":   buildSuggestionSlackMessage(request),
-		"mrkdwn": true,
-	})
-	if err != nil {
-		return err
-	}
-
-	httpRequest, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(payload))
-	if err != nil {
-		return err
-	}
-	httpRequest.Header.Set("Content-Type", "application/json")
-
-	response, err := s.client.Do(httpRequest)
-	if err != nil {
-		return err
+func buildSuggestionSlackMessage(request requestData) string {
+	status := suggestionStatus(request.Allowed)
+	var lines []string
+	if request.CatalogStatus != "" {
+		lines = []string{
+			"*AIP Food Lookup status dispute*",
+			"*Food:* " + escapeSlackValue(request.CatalogName),
+			"*Catalog status:* " + escapeSlackValue(request.CatalogStatus),
+			"*Claimed as:* " + escapeSlackValue(status),
+		}
+	} else {
+		lines = []string{
+			"*AIP Food Lookup suggestion*",
+			"*Food:* " + escapeSlackValue(request.InputText),
+			"*Suggested as:* " + escapeSlackValue(status),
+		}
 	}
-	defer response.Body.Close()
-
-	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return fmt.Errorf("slack returned status %d", response.StatusCode)
+	if request.Category != "" {
+		lines = append(lines, "*Category:* "+escapeSlackValue(request.Category))
 	}
-	return nil
-}
-
-func buildSuggestionSlackMessage(request requestData) string {
-	status := "not allowed"
-	if request.Allowed {
-		status = "allowed"
+	if request.Note != "" {
+		lines = append(lines, "*Note:* "+escapeSlackValue(request.Note))
 	}
-
-	return fmt.Sprintf(
-		"*AIP Food Lookup suggestion*\n*Food:* %s\n*Suggested as:* %s",
-		escapeSlackValue(request.InputText),
-		escapeSlackValue(status),
-	)
+	return strings.Join(lines, "\n")
 }
@@ -20,8 +20,9 @@ func TestSuggestHandlerSucceedsAndLogsWhenSlackFails(t *testing.T) {
 	store = newFoodStore(tempDir)
 	store.errorLogPath = filepath.Join(tempDir, "errors.log")
 	store.suggestionSink = newSuggestionSink(appConfig{
+		ErrorLogPath:            store.errorLogPath,
 		SlackFeedbackWebhookURL: slackServer.URL,
-	})
+	}, nil)
 
 	body := strings.NewReader(`{"inputText":"cassava chips","allowed":true}`)
 	request := httptest.NewRequest(http.MethodPost, "/suggest", body)
@@ -33,7 +34,7 @@ func TestSuggestHandlerSucceedsAndLogsWhenSlackFails(t *testing.T) {
 		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
 	}
 
-	content, err := os.ReadFile(filepath.Join(tempDir, "suggested_allowed.txt"))
+	content, err := os.ReadFile(filepath.Join(tempDir, suggestionStoreFileName))
 	if err != nil {
 		t.Fatalf("expected local suggestion file: %v", err)
 	}
@@ -66,8 +67,9 @@ func TestSuggestHandlerSucceedsAndLogsWhenLocalWriteFailsButSlackWorks(t *testin
 	store = newFoodStore(filepath.Join(tempDir, "missing"))
 	store.errorLogPath = filepath.Join(tempDir, "errors.log")
 	store.suggestionSink = newSuggestionSink(appConfig{
+		ErrorLogPath:            store.errorLogPath,
 		SlackFeedbackWebhookURL: slackServer.URL,
-	})
+	}, nil)
 
 	body := strings.NewReader(`{"inputText":"cassava chips","allowed":false}`)
 	request := httptest.NewRequest(http.MethodPost, "/suggest", body)
@@ -88,11 +90,38 @@ func TestSuggestHandlerSucceedsAndLogsWhenLocalWriteFailsButSlackWorks(t *testin
 	if err != nil {
 		t.Fatalf("expected local write failure in error log: %v", err)
 	}
-	if !strings.Contains(string(errorLog), "suggestion file write failed") {
+	if !strings.Contains(string(errorLog), "file suggestion failed") {
 		t.Fatalf("expected local write failure log, got %q", string(errorLog))
 	}
 }
 
+func TestSuggestHandlerStoresSuggestionsWhenChainOmitsFile(t *testing.T) {
+	slackHits := 0
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slackHits++
+	}))
+	defer slackServer.Close()
+
+	tempDir := t.TempDir()
+	store = newFoodStore(tempDir)
+	store.suggestionSink = newSuggestionSink(appConfig{
+		SlackFeedbackWebhookURL: slackServer.URL,
+		Sinks:                   sinkChainConfig{Suggestions: []string{"slack"}, SuggestionsMode: sinkModeFirstSuccess},
+	}, nil)
+
+	response := httptest.NewRecorder()
+	suggestHandler(response, httptest.NewRequest(http.MethodPost, "/suggest", strings.NewReader(`{"inputText":"tigernut","allowed":true}`)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	if slackHits != 1 {
+		t.Fatalf("expected one Slack notification, got %d", slackHits)
+	}
+	if _, err := store.suggestions.get("tigernut"); err != nil {
+		t.Fatalf("expected the suggestion in the store: %v", err)
+	}
+}
+
 func TestBuildSuggestionSlackMessageEscapesSlackSpecialCharacters(t *testing.T) {
 	message := buildSuggestionSlackMessage(requestData{
 		InputText: "chips <maybe> & dip",
@@ -106,3 +135,162 @@ func TestBuildSuggestionSlackMessageEscapesSlackSpecialCharacters(t *testing.T)
 		t.Fatalf("expected escaped Slack value, got %q", message)
 	}
 }
+
+func TestBuildSuggestionSlackMessageOmitsEmptyCategoryAndNote(t *testing.T) {
+	message := buildSuggestionSlackMessage(requestData{InputText: "sumac", Allowed: true})
+	if strings.Contains(message, "*Category:*") || strings.Contains(message, "*Note:*") {
+		t.Fatalf("expected no empty Category or Note lines, got %q", message)
+	}
+
+	message = buildSuggestionSlackMessage(requestData{InputText: "Ghee", CatalogName: "Ghee", CatalogStatus: "not allowed", Allowed: true, Note: "clarified"})
+	if strings.Contains(message, "*Category:*") || !strings.HasSuffix(message, "*Note:* clarified") {
+		t.Fatalf("expected only the Note line, got %q", message)
+	}
+}
+
+func TestSuggestHandlerRecordsStatusDisputeForCatalogFood(t *testing.T) {
+	var slackPayload struct {
+		Text string `json:"text"`
+	}
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&slackPayload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slackServer.Close()
+
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "oils.yaml", "- name: Ghee\n")
+	store = newFoodStore(tempDir)
+	store.suggestionSink = newSuggestionSink(appConfig{SlackFeedbackWebhookURL: slackServer.URL}, nil)
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{`{"inputText":"ghee","allowed":true}`, `{"inputText":"Ghee","allowed":false}`, `{"inputText":"ghee","allowed":true}`} {
+		response := httptest.NewRecorder()
+		suggestHandler(response, httptest.NewRequest(http.MethodPost, "/suggest", strings.NewReader(body)))
+		if response.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+		}
+		if strings.Contains(body, "false") && (!strings.Contains(slackPayload.Text, "status dispute") ||
+			!strings.Contains(slackPayload.Text, "*Catalog status:* allowed") || !strings.Contains(slackPayload.Text, "*Claimed as:* not allowed")) {
+			t.Fatalf("expected dispute Slack message, got %q", slackPayload.Text)
+		}
+	}
+
+	response := httptest.NewRecorder()
+	adminDisputesHandler(response, httptest.NewRequest(http.MethodGet, adminDisputesPath, nil))
+	var list adminDisputeListResponse
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Disputes) != 1 {
+		t.Fatalf("expected one dispute, got %#v", list.Disputes)
+	}
+	dispute := list.Disputes[0]
+	if dispute.Food != "Ghee" || dispute.CatalogStatus != suggestionStatusAllowed || dispute.NotAllowedVotes != 1 || dispute.AllowedVotes != 1 {
+		t.Fatalf("expected the opening agreeing vote to be skipped, got %#v", dispute)
+	}
+	if suggestions, _ := store.suggestions.list(suggestionStateAll, false); len(suggestions) != 0 {
+		t.Fatalf("expected disputes to stay out of the suggestion store, got %#v", suggestions)
+	}
+}
+
+func TestSuggestHandlerValidatesAndStoresCategoryAndNote(t *testing.T) {
+	var slackPayload struct {
+		Text string `json:"text"`
+	}
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&slackPayload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slackServer.Close()
+
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "herbs_spices.yaml", "- name: Basil\n")
+	writeTestCatalogFile(t, tempDir, "not_allowed", "seeds.yaml", "- name: Chia\n")
+	store = newFoodStore(tempDir)
+	store.suggestionSink = newSuggestionSink(appConfig{SlackFeedbackWebhookURL: slackServer.URL}, nil)
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+
+	response := httptest.NewRecorder()
+	suggestHandler(response, httptest.NewRequest(http.MethodPost, "/suggest", strings.NewReader(`{"inputText":"lemongrass","allowed":true,"category":"Seeds"}`)))
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("expected not allowed category to be rejected for an allowed suggestion, got %d", response.Code)
+	}
+
+	response = httptest.NewRecorder()
+	suggestHandler(response, httptest.NewRequest(http.MethodPost, "/suggest", strings.NewReader(`{"inputText":"lemongrass","allowed":true,"category":"herbs and spices","note":"Fresh stalks, in Thai food"}`)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	if !strings.Contains(slackPayload.Text, "*Category:* Herbs and Spices") || !strings.Contains(slackPayload.Text, "*Note:* Fresh stalks, in Thai food") {
+		t.Fatalf("expected category and note in Slack message, got %q", slackPayload.Text)
+	}
+	record, err := store.suggestions.get("lemongrass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.CategoryHints["Herbs and Spices"] != 1 || len(record.Notes) != 1 || record.Notes[0] != "Fresh stalks, in Thai food" {
+		t.Fatalf("expected category hint and note to be stored, got %#v", record)
+	}
+}
+
+func TestSuggestHandlerKeepsAccentsAndFoldsForMatching(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "dairy.yaml", "- name: Crème Fraîche\n")
+	store = newFoodStore(tempDir)
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{`{"inputText":"Açaí  bowl","allowed":true,"note":"Topped with jalapeño"}`, `{"inputText":"acai bowl","allowed":true}`, `{"inputText":"creme fraiche","allowed":false}`} {
+		response := httptest.NewRecorder()
+		suggestHandler(response, httptest.NewRequest(http.MethodPost, "/suggest", strings.NewReader(body)))
+		if response.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+		}
+	}
+
+	record, err := store.suggestions.get("Açaí bowl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Text != "acai bowl" || record.Display != "Açaí bowl" || record.Count != 2 || len(record.Notes) != 1 || record.Notes[0] != "Topped with jalapeño" {
+		t.Fatalf("expected one folded record keeping the submitted spelling, got %#v", record)
+	}
+	if name := record.displayName(); name != "Açaí bowl" {
+		t.Fatalf("display name = %q", name)
+	}
+	dispute, err := store.disputes.get("creme fraiche")
+	if err != nil || dispute.Food != "Crème Fraîche" {
+		t.Fatalf("expected ASCII spelling to dispute the accented catalog food, got %#v, %v", dispute, err)
+	}
+}
+
+func TestSuggestHandlerTreatsPluralOfCatalogFoodAsDispute(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestCatalogFile(t, tempDir, "allowed", "fruits.yaml", "- name: Cherries\n- name: Cherry\n- name: Avocados\n")
+	store = newFoodStore(tempDir)
+	if err := store.processDirectory(tempDir); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.nameFoods) != 3 {
+		t.Fatalf("expected plural and singular names to stay separate foods, got %d", len(store.nameFoods))
+	}
+
+	response := httptest.NewRecorder()
+	suggestHandler(response, httptest.NewRequest(http.MethodPost, "/suggest", strings.NewReader(`{"inputText":"avocado","allowed":false}`)))
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", response.Code, response.Body.String())
+	}
+	dispute, err := store.disputes.get("avocado")
+	if err != nil || dispute.Food != "Avocados" || dispute.NotAllowedVotes != 1 {
+		t.Fatalf("expected a dispute against Avocados, got %#v, %v", dispute, err)
+	}
+	if suggestions, _ := store.suggestions.list(suggestionStateAll, false); len(suggestions) != 0 {
+		t.Fatalf("expected no new suggestion, got %#v", suggestions)
+	}
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+const (
+	suggestionStateAll      = "all"
+	suggestionStatePending  = "pending"
+	suggestionStateAccepted = "accepted"
+	suggestionStateRejected = "rejected"
+
+	suggestionStatusAllowed    = "allowed"
+	suggestionStatusNotAllowed = "not allowed"
+
+	suggestionStoreFileName = "suggestions.json"
+	disputeStoreFileName    = "disputes.json"
+	clientKeyFileName       = "suggestion-client.key"
+	suggestionNoteLimit     = 20
+
+	// clientFilterBytes sizes the per-status Bloom filter of client hashes:
+	// 4096 bits with clientFilterHashes probes keep false repeats near 3% at
+	// 500 distinct clients.
+	clientFilterBytes  = 512
+	clientFilterHashes = 3
+)
+
+var errSuggestionNotFound = errors.New("suggestion not found")
+
+// suggestionRecord is one moderated user suggestion. Votes and distinct
+// clients are counted per status; Status is the leading one, and Contested is
+// set once both statuses have votes. CategoryHints counts the categories users
+// proposed and Notes keeps their most recent notes. Category is set when a
+// curator accepts it into the catalog. Status disputes about existing catalog
+// foods use the same record with Food and CatalogStatus set. Text is the folded
+// match key; Display keeps the first submitted spelling when it had accents.
+// ClientFilters holds a fixed-size Bloom filter of client hashes per status;
+// ClientHashes is only read from older files and migrated into it.
+type suggestionRecord struct {
+	Text              string              `json:"text"`
+	Display           string              `json:"display,omitempty"`
+	Food              string              `json:"food,omitempty"`
+	CatalogStatus     string              `json:"catalogStatus,omitempty"`
+	Status            string              `json:"status"`
+	Count             int                 `json:"count"`
+	AllowedVotes      int                 `json:"allowedVotes"`
+	NotAllowedVotes   int                 `json:"notAllowedVotes"`
+	AllowedClients    int                 `json:"allowedClients"`
+	NotAllowedClients int                 `json:"notAllowedClients"`
+	Contested         bool                `json:"contested"`
+	FirstSeen         time.Time           `json:"firstSeen"`
+	LastSeen          time.Time           `json:"lastSeen"`
+	State             string              `json:"state"`
+	CategoryHints     map[string]int      `json:"categoryHints,omitempty"`
+	Notes             []string            `json:"notes,omitempty"`
+	Category          string              `json:"category,omitempty"`
+	DecidedAt         *time.Time          `json:"decidedAt,omitempty"`
+	ClientFilters     map[string][]byte   `json:"clientFilters,omitempty"`
+	ClientHashes      map[string][]string `json:"clientHashes,omitempty"`
+}
+
+// suggestionStore keeps suggestion records in one JSON file that is loaded on
+// first use and rewritten atomically on every change. It outlives catalog
+// reloads, so every foodStore generation shares the same instance.
+//
+// Clients are identified by an HMAC of their IP address, so only keyed hashes
+// are persisted. Without a configured key, a random key is generated once and
+// kept in suggestion-client.key beside the store so counts survive restarts.
+type suggestionStore struct {
+	mu        sync.Mutex
+	path      string
+	loaded    bool
+	records   map[string]*suggestionRecord
+	clientKey string
+	now       func() time.Time
+}
+
+func newSuggestionStore(path string) *suggestionStore {
+	return &suggestionStore{
+		path:    path,
+		records: make(map[string]*suggestionRecord),
+		now:     time.Now,
+	}
+}
+
+// loadClientKey reads the generated client key, creating it on first use.
+func loadClientKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	encoded := hex.EncodeToString(key)
+	if err := os.WriteFile(path, []byte(encoded+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return encoded, nil
+}
+
+func suggestionStatus(allowed bool) string {
+	if allowed {
+		return suggestionStatusAllowed
+	}
+	return suggestionStatusNotAllowed
+}
+
+// suggestionVote is one /suggest request as counted by the store. Food and
+// CatalogStatus are set for votes on an existing catalog food.
+type suggestionVote struct {
+	Text          string
+	Allowed       bool
+	ClientIP      string
+	Category      string
+	Note          string
+	Food          string
+	CatalogStatus string
+}
+
+// record counts one vote. Only pending records count toward the limit, so
+// accepting or rejecting suggestions frees room for new ones. Votes on a
+// catalog food that agree with it only count once someone has disputed it,
+// so admins see both sides without every confirming suggestion being stored.
+func (s *suggestionStore) record(vote suggestionVote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	key := foodcatalog.Fold(vote.Text)
+	record := s.records[key]
+	if record == nil {
+		if vote.CatalogStatus != "" && vote.CatalogStatus == suggestionStatus(vote.Allowed) {
+			return nil
+		}
+		if s.countState(suggestionStatePending) >= allowedNotAllowedLimit {
+			return errors.New("suggestion limit exceeded")
+		}
+		record = &suggestionRecord{
+			Text:      key,
+			Status:    suggestionStatus(vote.Allowed),
+			FirstSeen: now,
+			State:     suggestionStatePending,
+		}
+		if display := strings.Join(strings.Fields(vote.Text), " "); strings.ToLower(display) != key {
+			record.Display = display
+		}
+		s.records[key] = record
+	}
+	if vote.CatalogStatus != "" {
+		record.Food, record.CatalogStatus = vote.Food, vote.CatalogStatus
+	}
+	record.addVote(vote.Allowed, hashIP(s.clientKey, vote.ClientIP))
+	if vote.Category != "" {
+		if record.CategoryHints == nil {
+			record.CategoryHints = make(map[string]int)
+		}
+		record.CategoryHints[vote.Category]++
+	}
+	if vote.Note != "" {
+		record.Notes = append(record.Notes, vote.Note)
+		if len(record.Notes) > suggestionNoteLimit {
+			record.Notes = record.Notes[len(record.Notes)-suggestionNoteLimit:]
+		}
+	}
+	record.LastSeen = now
+	return s.save()
+}
+
+// addVote counts a vote and, for a known client not yet in the status's
+// client filter, a new distinct client.
+func (r *suggestionRecord) addVote(allowed bool, clientHash string) {
+	votes, clients := &r.NotAllowedVotes, &r.NotAllowedClients
+	if allowed {
+		votes, clients = &r.AllowedVotes, &r.AllowedClients
+	}
+	*votes++
+	r.Count++
+	if clientHash != "" && r.addClient(suggestionStatus(allowed), clientHash) {
+		*clients++
+	}
+	if r.AllowedVotes > r.NotAllowedVotes {
+		r.Status = suggestionStatusAllowed
+	} else if r.NotAllowedVotes > r.AllowedVotes {
+		r.Status = suggestionStatusNotAllowed
+	}
+	r.Contested = r.AllowedVotes > 0 && r.NotAllowedVotes > 0
+}
+
+// topCategoryHint returns the most proposed category, preferring the
+// alphabetically first on ties.
+func (r suggestionRecord) topCategoryHint() string {
+	top, topVotes := "", 0
+	for category, votes := range r.CategoryHints {
+		if votes > topVotes || (votes == topVotes && category < top) {
+			top, topVotes = category, votes
+		}
+	}
+	return top
+}
+
+// displayName is the catalog name an accepted suggestion gets by default: the
+// submitted spelling when it was kept, capitalized.
+func (r suggestionRecord) displayName() string {
+	name := r.Text
+	if r.Display != "" {
+		name = r.Display
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
+
+// addClient adds clientHash to the status's Bloom filter and reports whether
+// it was new. A false "already seen" undercounts distinct clients slightly
+// but never overcounts them.
+func (r *suggestionRecord) addClient(status string, clientHash string) bool {
+	if r.ClientFilters == nil {
+		r.ClientFilters = make(map[string][]byte)
+	}
+	filter := r.ClientFilters[status]
+	if len(filter) != clientFilterBytes {
+		filter = make([]byte, clientFilterBytes)
+		r.ClientFilters[status] = filter
+	}
+	sum := sha256.Sum256([]byte(clientHash))
+	added := false
+	for probe := 0; probe < clientFilterHashes; probe++ {
+		bit := binary.BigEndian.Uint32(sum[probe*4:]) % (clientFilterBytes * 8)
+		if filter[bit/8]&(1<<(bit%8)) == 0 {
+			filter[bit/8] |= 1 << (bit % 8)
+			added = true
+		}
+	}
+	return added
+}
+
+// migrateClientHashes moves client hash lists from older files into the
+// Bloom filters without changing the stored client counts.
+func (r *suggestionRecord) migrateClientHashes() {
+	for status, hashes := range r.ClientHashes {
+		for _, hash := range hashes {
+			r.addClient(status, hash)
+		}
+	}
+	r.ClientHashes = nil
+}
+
+// public hides client hashes from admin responses.
+func (r suggestionRecord) public() suggestionRecord {
+	r.ClientFilters = nil
+	r.ClientHashes = nil
+	return r
+}
+
+// list returns records in state (or every record for "all"), most suggested
+// first. contestedOnly keeps records with votes for both statuses.
+func (s *suggestionStore) list(state string, contestedOnly bool) ([]suggestionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	result := []suggestionRecord{}
+	for _, record := range s.records {
+		if (state == suggestionStateAll || record.State == state) && (!contestedOnly || record.Contested) {
+			result = append(result, record.public())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Text < result[j].Text
+	})
+	return result, nil
+}
+
+func (s *suggestionStore) get(text string) (suggestionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return suggestionRecord{}, err
+	}
+
+	record := s.records[foodcatalog.Fold(text)]
+	if record == nil {
+		return suggestionRecord{}, errSuggestionNotFound
+	}
+	return record.public(), nil
+}
+
+// decide moves a record to accepted or rejected, recording the catalog
+// status and category it was accepted with.
+func (s *suggestionStore) decide(text string, state string, status string, category string) (suggestionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return suggestionRecord{}, err
+	}
+
+	record := s.records[foodcatalog.Fold(text)]
+	if record == nil {
+		return suggestionRecord{}, errSuggestionNotFound
+	}
+	decidedAt := s.now().UTC()
+	record.State = state
+	record.DecidedAt = &decidedAt
+	if status != "" {
+		record.Status = status
+	}
+	record.Category = category
+	return record.public(), s.save()
+}
+
+// importLegacy adds lines from the old suggested_*.txt files as pending
+// records, skipping texts the store already knows.
+func (s *suggestionStore) importLegacy(filePath string, allowed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key := foodcatalog.Fold(scanner.Text())
+		if key == "" || s.records[key] != nil {
+			continue
+		}
+		record := &suggestionRecord{
+			Text:      key,
+			Status:    suggestionStatus(allowed),
+			FirstSeen: info.ModTime().UTC(),
+			LastSeen:  info.ModTime().UTC(),
+			State:     suggestionStatePending,
+		}
+		record.addVote(allowed, "")
+		s.records[key] = record
+		changed = true
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+func (s *suggestionStore) countState(state string) int {
+	count := 0
+	for _, record := range s.records {
+		if record.State == state {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *suggestionStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	if s.clientKey == "" {
+		key, err := loadClientKey(filepath.Join(filepath.Dir(s.path), clientKeyFileName))
+		if err != nil {
+			return fmt.Errorf("load suggestion client key: %w", err)
+		}
+		s.clientKey = key
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var records []suggestionRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("decode suggestions %s: %w", s.path, err)
+	}
+	for index := range records {
+		record := &records[index]
+		if record.AllowedVotes+record.NotAllowedVotes == 0 && record.Count > 0 {
+			// Records written before per-status votes credit every vote to Status.
+			if record.Status == suggestionStatusAllowed {
+				record.AllowedVotes = record.Count
+			} else {
+				record.NotAllowedVotes = record.Count
+			}
+		}
+		record.migrateClientHashes()
+		s.records[record.Text] = record
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *suggestionStore) save() error {
+	records := make([]*suggestionRecord, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Text < records[j].Text
+	})
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporary := s.path + ".tmp"
+	if err := os.WriteFile(temporary, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, s.path)
+}
+
+// suggestionStorePath places the store beside the catalog unless configured.
+func suggestionStorePath(dataFolder string, configured string) string {
+	if strings.TrimSpace(configured) != "" {
+		return configured
+	}
+	return filepath.Join(dataFolder, suggestionStoreFileName)
+}
+
+// disputeStorePath keeps status disputes next to the suggestion store.
+func disputeStorePath(suggestionsPath string) string {
+	return filepath.Join(filepath.Dir(suggestionsPath), disputeStoreFileName)
+}
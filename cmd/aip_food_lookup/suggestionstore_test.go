@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSuggestionStoreCountsRepeatsAndPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), suggestionStoreFileName)
+	suggestions := newSuggestionStore(path)
+	first := time.Date(2026, 8, 18, 1, 0, 0, 0, time.UTC)
+	suggestions.now = func() time.Time { return first }
+	if err := suggestions.record(suggestionVote{Text: "Ghee", Allowed: true, ClientIP: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	suggestions.now = func() time.Time { return first.Add(time.Hour) }
+	if err := suggestions.record(suggestionVote{Text: "ghee ", Allowed: true, ClientIP: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := newSuggestionStore(path)
+	record, err := reopened.get("ghee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Count != 2 || record.State != suggestionStatePending || !record.FirstSeen.Equal(first) || !record.LastSeen.Equal(first.Add(time.Hour)) {
+		t.Fatalf("unexpected record %#v", record)
+	}
+}
+
+func TestSuggestionStoreImportsLegacyFilesOnce(t *testing.T) {
+	tempDir := t.TempDir()
+	legacy := filepath.Join(tempDir, "suggested_not_allowed.txt")
+	if err := os.WriteFile(legacy, []byte("Corn syrup\n\ncorn syrup\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store = newFoodStore(tempDir)
+	for i := 0; i < 2; i++ {
+		if err := store.processDirectory(tempDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err := store.suggestions.list(suggestionStatePending, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Text != "corn syrup" || pending[0].Status != suggestionStatusNotAllowed || pending[0].Count != 1 {
+		t.Fatalf("unexpected imported suggestions %#v", pending)
+	}
+}
+
+func TestSuggestionStoreAggregatesVotesPerStatus(t *testing.T) {
+	suggestions := newSuggestionStore(filepath.Join(t.TempDir(), suggestionStoreFileName))
+	votes := []struct {
+		allowed bool
+		client  string
+	}{
+		{true, "192.0.2.1"},
+		{false, "192.0.2.2"},
+		{false, "192.0.2.3"},
+		{false, "192.0.2.3"},
+	}
+	for _, vote := range votes {
+		if err := suggestions.record(suggestionVote{Text: "ghee", Allowed: vote.allowed, ClientIP: vote.client}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	record, err := suggestions.get("ghee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.AllowedVotes != 1 || record.NotAllowedVotes != 3 || record.AllowedClients != 1 || record.NotAllowedClients != 2 {
+		t.Fatalf("unexpected vote counts %#v", record)
+	}
+	if record.Status != suggestionStatusNotAllowed || !record.Contested || record.Count != 4 || record.ClientFilters != nil {
+		t.Fatalf("unexpected aggregate %#v", record)
+	}
+	contested, err := suggestions.list(suggestionStatePending, true)
+	if err != nil || len(contested) != 1 {
+		t.Fatalf("expected ghee in contested listing, got %#v err=%v", contested, err)
+	}
+}
+
+func TestSuggestionStoreKeepsGeneratedClientKeyAcrossRestarts(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, suggestionStoreFileName)
+	if err := newSuggestionStore(path).record(suggestionVote{Text: "ghee", Allowed: true, ClientIP: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newSuggestionStore(path)
+	for _, client := range []string{"192.0.2.1", "192.0.2.2"} {
+		if err := restarted.record(suggestionVote{Text: "ghee", Allowed: true, ClientIP: client}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record, err := restarted.get("ghee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.AllowedVotes != 3 || record.AllowedClients != 2 {
+		t.Fatalf("expected the returning client to be recognized after a restart, got %#v", record)
+	}
+	if info, err := os.Stat(filepath.Join(tempDir, clientKeyFileName)); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected a private client key file, got %v err=%v", info, err)
+	}
+}
+
+func TestSuggestionStoreMigratesClientHashListsIntoFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), suggestionStoreFileName)
+	legacy := `[{"text":"ghee","status":"allowed","count":1,"allowedVotes":1,"allowedClients":1,"state":"pending","clientHashes":{"allowed":["` + hashIP("key", "192.0.2.1") + `"]}}]`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	suggestions := newSuggestionStore(path)
+	suggestions.clientKey = "key"
+	if err := suggestions.record(suggestionVote{Text: "ghee", Allowed: true, ClientIP: "192.0.2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	record, _ := suggestions.get("ghee")
+	if record.AllowedVotes != 2 || record.AllowedClients != 1 {
+		t.Fatalf("expected the migrated client to count once, got %#v", record)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "clientHashes") || !strings.Contains(string(data), "clientFilters") {
+		t.Fatalf("expected hash lists to be replaced by filters, got %s", data)
+	}
+}
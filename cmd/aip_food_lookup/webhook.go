@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+const (
+	webhookSignatureHeader = "X-Signature"
+	webhookTimestampHeader = "X-Signature-Timestamp"
+	webhookEventFeedback   = "feedback"
+	webhookEventSuggestion = "suggestion"
+	webhookDefaultTimeout  = 5 * time.Second
+)
+
+// webhookSink posts feedback and suggestions as structured JSON to a generic
+// webhook, such as a ticketing service. Each request carries an X-Signature
+// of "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>", keyed
+// with the secret, where timestamp is the X-Signature-Timestamp header in
+// Unix seconds. Receivers should recompute it and reject stale timestamps.
+type webhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+	now    func() time.Time
+}
+
+// webhookEvent is the JSON body; exactly one of Feedback and Suggestion is set.
+type webhookEvent struct {
+	Event      string             `json:"event"`
+	SentAt     time.Time          `json:"sentAt"`
+	Feedback   *feedbackRequest   `json:"feedback,omitempty"`
+	Suggestion *webhookSuggestion `json:"suggestion,omitempty"`
+}
+
+type webhookSuggestion struct {
+	Text          string `json:"text"`
+	Status        string `json:"status"`
+	Category      string `json:"category,omitempty"`
+	Note          string `json:"note,omitempty"`
+	Food          string `json:"food,omitempty"`
+	CatalogStatus string `json:"catalogStatus,omitempty"`
+}
+
+// newWebhookSink returns nil when no webhook URL or secret is configured;
+// requests are never sent unsigned. A timeout of zero or less uses
+// webhookDefaultTimeout, so a slow receiver cannot hold a request forever.
+func newWebhookSink(config appConfig) *webhookSink {
+	if strings.TrimSpace(config.Webhook.URL) == "" || config.Webhook.Secret == "" {
+		return nil
+	}
+	timeout := time.Duration(config.Webhook.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = webhookDefaultTimeout
+	}
+	return &webhookSink{
+		url:    config.Webhook.URL,
+		secret: config.Webhook.Secret,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		now: time.Now,
+	}
+}
+
+func (s *webhookSink) submitFeedback(request feedbackRequest) error {
+	return s.post(webhookEvent{Event: webhookEventFeedback, Feedback: &request})
+}
+
+func (s *webhookSink) submitSuggestion(request requestData) error {
+	return s.post(webhookEvent{
+		Event: webhookEventSuggestion,
+		Suggestion: &webhookSuggestion{
+			Text:          foodcatalog.Fold(request.InputText),
+			Status:        suggestionStatus(request.Allowed),
+			Category:      request.Category,
+			Note:          request.Note,
+			Food:          request.CatalogName,
+			CatalogStatus: request.CatalogStatus,
+		},
+	})
+}
+
+func (s *webhookSink) post(event webhookEvent) error {
+	now := s.now().UTC()
+	event.SentAt = now
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	httpRequest, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set(webhookTimestampHeader, timestamp)
+	httpRequest.Header.Set(webhookSignatureHeader, webhookSignature(s.secret, timestamp, payload))
+
+	response, err := s.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &webhookStatusError{Service: "webhook", StatusCode: response.StatusCode}
+	}
+	return nil
+}
+
+func webhookSignature(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookSinkSignsFeedbackAndSuggestions(t *testing.T) {
+	var events []webhookEvent
+	var signatures []string
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(webhookTimestampHeader)
+		signatures = append(signatures, r.Header.Get(webhookSignatureHeader))
+		if sent, _ := strconv.ParseInt(timestamp, 10, 64); sent != 1767323045 {
+			t.Errorf("timestamp header = %q", timestamp)
+		}
+		var event webhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+		events = append(events, event)
+	}))
+	defer webhookServer.Close()
+
+	sink := newWebhookSink(appConfig{Webhook: webhookConfig{URL: webhookServer.URL, Secret: "s3cret", TimeoutSeconds: 5}})
+	sink.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	if err := sink.submitFeedback(feedbackRequest{Name: "Joe", Email: "joe@example.com", Message: "Hi"}); err != nil {
+		t.Fatalf("submitFeedback: %v", err)
+	}
+	if err := sink.submitSuggestion(requestData{InputText: " CASSÁVA ", Allowed: false, Category: "Flours", CatalogName: "Cassava", CatalogStatus: "allowed"}); err != nil {
+		t.Fatalf("submitSuggestion: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("events = %#v", events)
+	}
+	// HMAC-SHA256 of "1767323045." plus the feedback body, keyed with s3cret,
+	// computed independently of webhookSignature.
+	if want := "sha256=f1115c254ad576c43ed72bda438a80c69b1aceec18927f6daf97e57aad521c2a"; signatures[0] != want {
+		t.Fatalf("feedback signature = %q, want %q", signatures[0], want)
+	}
+	if events[0].Event != webhookEventFeedback || events[0].Feedback == nil || events[0].Feedback.Email != "joe@example.com" {
+		t.Fatalf("unexpected feedback event %#v", events[0])
+	}
+	suggestion := events[1].Suggestion
+	if events[1].Event != webhookEventSuggestion || suggestion == nil || suggestion.Text != "cassava" || suggestion.Status != "not allowed" || suggestion.CatalogStatus != "allowed" || suggestion.Category != "Flours" {
+		t.Fatalf("unexpected suggestion event %#v", events[1])
+	}
+}
+
+func TestWebhookSinkFallsBackToJsonlForFeedback(t *testing.T) {
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer webhookServer.Close()
+
+	tempDir := t.TempDir()
+	config := appConfig{
+		DataFolder:   tempDir,
+		ErrorLogPath: filepath.Join(tempDir, "errors.log"),
+		Webhook:      webhookConfig{URL: webhookServer.URL, Secret: "s3cret", TimeoutSeconds: 5},
+	}
+	store = newFoodStore(tempDir)
+	store.feedbackSink = newFeedbackSink(config, nil)
+	if err := store.submitFeedback(feedbackRequest{Message: "Works?"}); err != nil {
+		t.Fatalf("expected the stored feedback to hide the webhook error, got %v", err)
+	}
+	if err := newWebhookSink(config).submitSuggestion(requestData{InputText: "ghee"}); err == nil || !strings.Contains(err.Error(), "status 502") {
+		t.Fatalf("expected webhook suggestion error, got %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "feedback.jsonl"))
+	if err != nil || !strings.Contains(string(content), `"message":"Works?"`) {
+		t.Fatalf("expected fallback feedback file, got %q err %v", content, err)
+	}
+	errorLog, _ := os.ReadFile(filepath.Join(tempDir, "errors.log"))
+	if !strings.Contains(string(errorLog), "webhook feedback failed: webhook returned status 502") {
+		t.Fatalf("expected webhook failure log, got %q", errorLog)
+	}
+}
+
+func TestWebhookSignatureKnownVector(t *testing.T) {
+	want := "sha256=9d713ed406bb7076d4123f0dc2c39d2df5c654ed4b0cd56b52c8b4c940bd63ae"
+	if got := webhookSignature("key", "1700000000", []byte("{}")); got != want {
+		t.Fatalf("webhookSignature = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookSinkRequiresSecret(t *testing.T) {
+	tempDir := t.TempDir()
+	config := appConfig{
+		DataFolder:   tempDir,
+		ErrorLogPath: filepath.Join(tempDir, "errors.log"),
+		Webhook:      webhookConfig{URL: "https://hooks.example", TimeoutSeconds: 5},
+	}
+	if newWebhookSink(config) != nil {
+		t.Fatal("expected no webhook sink without a secret")
+	}
+	newFeedbackSink(config, nil)
+	errorLog, _ := os.ReadFile(config.ErrorLogPath)
+	if !strings.Contains(string(errorLog), `feedback sink "webhook" skipped: webhook secret is not configured`) {
+		t.Fatalf("expected a startup warning, got %q", errorLog)
+	}
+}
+
+func TestWebhookSinkDefaultsToFiniteTimeout(t *testing.T) {
+	sink := newWebhookSink(appConfig{Webhook: webhookConfig{URL: "https://hooks.example", Secret: "s3cret"}})
+	if sink.client.Timeout != webhookDefaultTimeout {
+		t.Fatalf("expected the default timeout, got %v", sink.client.Timeout)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+const (
+	suggestionVoteWeight  = 5
+	feedbackMentionWeight = 10
+
+	catalogStatusAllowed    = "allowed"
+	catalogStatusNotAllowed = "not allowed"
+)
+
+// backlogItem is one curation candidate merged from searches, suggestions and
+// feedback. Score weights a suggestion vote as five searches and a feedback
+// mention as ten.
+type backlogItem struct {
+	Text             string `json:"text"`
+	Score            int    `json:"score"`
+	Searches         int    `json:"searches"`
+	Covered          bool   `json:"covered"`
+	AllowedVotes     int    `json:"allowedVotes"`
+	NotAllowedVotes  int    `json:"notAllowedVotes"`
+	FeedbackMentions int    `json:"feedbackMentions"`
+	CatalogStatus    string `json:"catalogStatus,omitempty"`
+	Contradiction    bool   `json:"contradiction"`
+}
+
+// storedSuggestion mirrors the API's suggestions.json record.
+type storedSuggestion struct {
+	Text            string `json:"text"`
+	Status          string `json:"status"`
+	Count           int    `json:"count"`
+	AllowedVotes    int    `json:"allowedVotes"`
+	NotAllowedVotes int    `json:"notAllowedVotes"`
+	State           string `json:"state"`
+}
+
+// backlogFeedback is the subset of feedback.jsonl records searched for mentions.
+type backlogFeedback struct {
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func buildBacklog(args []string) error {
+	flags := flag.NewFlagSet("backlog", flag.ContinueOnError)
+	input := flags.String("input", "searches.tsv", "TSV, JSON or CSV exported by extract mode; empty to skip")
+	catalog := flags.String("catalog", "../../data", "local repository data directory")
+	runtimeData := flags.String("runtime-data", "../../data", "directory holding suggestions.json (or legacy suggested_*.txt), disputes.json and feedback.jsonl")
+	format := flags.String("format", formatText, "output format: text, json or csv")
+	output := flags.String("output", "-", "output path, or - for stdout")
+	options := matchOptionFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatText, formatJSON, formatCSV); err != nil {
+		return err
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	searches := make(map[string]loggedSearch)
+	if *input != "" {
+		var err error
+		if searches, err = readSearchExport(*input); err != nil {
+			return err
+		}
+	}
+	foods, err := foodcatalog.Load(*catalog)
+	if err != nil {
+		return fmt.Errorf("load local catalog: %w", err)
+	}
+	allowedVotes, notAllowedVotes, err := readStoredSuggestionVotes(filepath.Join(*runtimeData, "suggestions.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		if allowedVotes, err = readSuggestionVotes(filepath.Join(*runtimeData, "suggested_allowed.txt")); err != nil {
+			return err
+		}
+		if notAllowedVotes, err = readSuggestionVotes(filepath.Join(*runtimeData, "suggested_not_allowed.txt")); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	disputeAllowed, disputeNotAllowed, err := readStoredSuggestionVotes(filepath.Join(*runtimeData, "disputes.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for text, votes := range disputeAllowed {
+		allowedVotes[text] += votes
+	}
+	for text, votes := range disputeNotAllowed {
+		notAllowedVotes[text] += votes
+	}
+	feedback, err := readBacklogFeedback(filepath.Join(*runtimeData, "feedback.jsonl"))
+	if err != nil {
+		return err
+	}
+	items := mergeBacklog(searches, foods, allowedVotes, notAllowedVotes, feedback, *options)
+	return writeOutput(*output, func(writer io.Writer) error {
+		return writeBacklog(writer, *format, items)
+	})
+}
+
+// mergeBacklog ranks uncovered searches and suggestions together. Suggestions
+// that exactly name a catalog food are kept only when they contradict its
+// status, since agreeing ones need no curation.
+func mergeBacklog(searches map[string]loggedSearch, foods []foodcatalog.Food, allowedVotes map[string]int, notAllowedVotes map[string]int, feedback []string, options foodcatalog.MatchOptions) []backlogItem {
+	statuses := catalogStatuses(foods)
+	items := make(map[string]*backlogItem)
+	// Items are keyed by singular form, so "avocado" and "Avocados" share
+	// one row, shown under the lexically first spelling.
+	itemFor := func(text string) *backlogItem {
+		singular := foodcatalog.Singular(text)
+		item := items[singular]
+		if item == nil {
+			item = &backlogItem{Text: text, Covered: options.Covered(foods, text), CatalogStatus: statuses[singular]}
+			items[singular] = item
+		} else if text < item.Text {
+			item.Text = text
+		}
+		return item
+	}
+
+	for key, search := range searches {
+		key = normalizeBacklogText(key)
+		if key == "" || options.Covered(foods, key) {
+			continue
+		}
+		itemFor(key).Searches += search.Count
+	}
+	for text, votes := range allowedVotes {
+		itemFor(text).AllowedVotes += votes
+	}
+	for text, votes := range notAllowedVotes {
+		itemFor(text).NotAllowedVotes += votes
+	}
+
+	result := make([]backlogItem, 0, len(items))
+	for _, item := range items {
+		if search, exists := searches[item.Text]; exists && item.Searches == 0 {
+			item.Searches = search.Count
+		}
+		item.Contradiction = contradictsCatalog(item)
+		if item.CatalogStatus != "" && !item.Contradiction {
+			continue
+		}
+		item.FeedbackMentions = countMentions(item.Text, feedback)
+		item.Score = item.Searches + suggestionVoteWeight*(item.AllowedVotes+item.NotAllowedVotes) + feedbackMentionWeight*item.FeedbackMentions
+		result = append(result, *item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Text < result[j].Text
+	})
+	return result
+}
+
+func contradictsCatalog(item *backlogItem) bool {
+	switch item.CatalogStatus {
+	case catalogStatusAllowed:
+		return item.NotAllowedVotes > 0
+	case catalogStatusNotAllowed:
+		return item.AllowedVotes > 0
+	default:
+		return false
+	}
+}
+
+// catalogStatuses maps the singular form of names and aliases to their
+// catalog status.
+func catalogStatuses(foods []foodcatalog.Food) map[string]string {
+	statuses := make(map[string]string)
+	for _, food := range foods {
+		status := catalogStatusNotAllowed
+		if food.Allowed {
+			status = catalogStatusAllowed
+		}
+		for _, name := range append([]string{food.Name}, food.Aliases...) {
+			statuses[foodcatalog.Singular(name)] = status
+		}
+	}
+	return statuses
+}
+
+func normalizeBacklogText(text string) string {
+	return foodcatalog.Fold(text)
+}
+
+func countMentions(text string, feedback []string) int {
+	pattern, err := regexp.Compile(`\b` + regexp.QuoteMeta(text) + `\b`)
+	if err != nil {
+		return 0
+	}
+	mentions := 0
+	for _, message := range feedback {
+		if pattern.MatchString(message) {
+			mentions++
+		}
+	}
+	return mentions
+}
+
+// readStoredSuggestionVotes counts pending suggestion store records by
+// status; accepted and rejected ones are already curated.
+func readStoredSuggestionVotes(path string) (map[string]int, map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var records []storedSuggestion
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, nil, fmt.Errorf("decode suggestions %s: %w", path, err)
+	}
+	allowedVotes, notAllowedVotes := make(map[string]int), make(map[string]int)
+	for _, record := range records {
+		text := normalizeBacklogText(record.Text)
+		if text == "" || record.State != "pending" {
+			continue
+		}
+		switch {
+		case record.AllowedVotes+record.NotAllowedVotes > 0:
+			allowedVotes[text] += record.AllowedVotes
+			notAllowedVotes[text] += record.NotAllowedVotes
+		case record.Status == catalogStatusAllowed:
+			allowedVotes[text] += record.Count
+		case record.Status == catalogStatusNotAllowed:
+			notAllowedVotes[text] += record.Count
+		}
+	}
+	return allowedVotes, notAllowedVotes, nil
+}
+
+// readSuggestionVotes counts lines per suggestion; a missing file has no votes.
+func readSuggestionVotes(path string) (map[string]int, error) {
+	votes := make(map[string]int)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return votes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open suggestions %s: %w", path, err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if text := normalizeBacklogText(scanner.Text()); text != "" {
+			votes[text]++
+		}
+	}
+	return votes, scanner.Err()
+}
+
+// readBacklogFeedback returns folded subject and message text per record that
+// has not been handled yet.
+func readBacklogFeedback(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open feedback %s: %w", path, err)
+	}
+	defer file.Close()
+	var messages []string
+	scanner := newLogScanner(file)
+	for scanner.Scan() {
+		var record backlogFeedback
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Status == "handled" {
+			continue
+		}
+		messages = append(messages, foodcatalog.Fold(record.Subject+"\n"+record.Message))
+	}
+	return messages, scanner.Err()
+}
+
+func writeBacklog(writer io.Writer, format string, items []backlogItem) error {
+	switch format {
+	case formatText:
+		fmt.Fprintln(writer, "score | text | searches | allowed votes | not allowed votes | feedback | catalog | flag")
+		fmt.Fprintln(writer, "------|------|----------|---------------|-------------------|----------|---------|-----")
+		for _, item := range items {
+			fmt.Fprintf(writer, "%5d | %s | %d | %d | %d | %d | %s | %s\n", item.Score, item.Text, item.Searches, item.AllowedVotes, item.NotAllowedVotes, item.FeedbackMentions, backlogCatalogLabel(item), backlogFlag(item))
+		}
+		return nil
+	case formatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+	case formatCSV:
+		csvWriter := csv.NewWriter(writer)
+		_ = csvWriter.Write([]string{"score", "text", "searches", "allowed_votes", "not_allowed_votes", "feedback_mentions", "catalog", "flag"})
+		for _, item := range items {
+			_ = csvWriter.Write([]string{
+				strconv.Itoa(item.Score),
+				item.Text,
+				strconv.Itoa(item.Searches),
+				strconv.Itoa(item.AllowedVotes),
+				strconv.Itoa(item.NotAllowedVotes),
+				strconv.Itoa(item.FeedbackMentions),
+				backlogCatalogLabel(item),
+				backlogFlag(item),
+			})
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	default:
+		return fmt.Errorf("unknown format %q; use text, json or csv", format)
+	}
+}
+
+func backlogCatalogLabel(item backlogItem) string {
+	if item.CatalogStatus != "" {
+		return item.CatalogStatus
+	}
+	if item.Covered {
+		return "covered"
+	}
+	return "uncovered"
+}
+
+func backlogFlag(item backlogItem) string {
+	if item.Contradiction {
+		return "contradicts catalog"
+	}
+	return "-"
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+func TestMergeBacklogRanksAndFlagsContradictions(t *testing.T) {
+	foods := loadTestCatalog(t, "Pork\nGhee\n")
+	searches := map[string]loggedSearch{
+		"cassava chips": {Count: 4},
+		"pork":          {Count: 50},
+		"tigernut":      {Count: 12},
+	}
+	allowedVotes := map[string]int{"cassava chips": 1, "pork": 1}
+	notAllowedVotes := map[string]int{"ghee": 2}
+	feedback := []string{"please add cassava chips\nthey are aip", "is ghee really ok?"}
+
+	items := mergeBacklog(searches, foods, allowedVotes, notAllowedVotes, feedback, foodcatalog.DefaultMatchOptions())
+	if len(items) != 3 {
+		t.Fatalf("expected agreeing pork suggestion to be dropped, got %#v", items)
+	}
+	if items[0].Text != "ghee" || !items[0].Contradiction || items[0].CatalogStatus != catalogStatusAllowed || items[0].Score != 20 {
+		t.Fatalf("expected ghee contradiction first, got %#v", items[0])
+	}
+	if items[1].Text != "cassava chips" || items[1].Score != 4+5+10 || items[1].FeedbackMentions != 1 {
+		t.Fatalf("unexpected cassava chips item: %#v", items[1])
+	}
+	if items[2].Text != "tigernut" || items[2].Searches != 12 || items[2].Covered {
+		t.Fatalf("unexpected tigernut item: %#v", items[2])
+	}
+}
+
+func TestReadSuggestionVotesToleratesMissingFile(t *testing.T) {
+	directory := t.TempDir()
+	votes, err := readSuggestionVotes(filepath.Join(directory, "suggested_allowed.txt"))
+	if err != nil || len(votes) != 0 {
+		t.Fatalf("unexpected votes %#v err=%v", votes, err)
+	}
+
+	path := filepath.Join(directory, "suggested_not_allowed.txt")
+	if err := os.WriteFile(path, []byte("Ghee\nghee\n\nrice cakes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	votes, err = readSuggestionVotes(path)
+	if err != nil || votes["ghee"] != 2 || votes["rice cakes"] != 1 {
+		t.Fatalf("unexpected votes %#v err=%v", votes, err)
+	}
+}
+
+func TestReadStoredSuggestionVotesCountsPendingRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suggestions.json")
+	records := `[
+  {"text":"ghee","status":"not allowed","count":3,"state":"pending"},
+  {"text":"lard","status":"allowed","count":3,"allowedVotes":2,"notAllowedVotes":1,"state":"pending"},
+  {"text":"plantain","status":"allowed","count":2,"state":"accepted"},
+  {"text":"tigernut","status":"allowed","count":1,"state":"pending"}
+]`
+	if err := os.WriteFile(path, []byte(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	allowedVotes, notAllowedVotes, err := readStoredSuggestionVotes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notAllowedVotes["ghee"] != 3 || allowedVotes["tigernut"] != 1 || allowedVotes["plantain"] != 0 || allowedVotes["lard"] != 2 || notAllowedVotes["lard"] != 1 {
+		t.Fatalf("unexpected votes allowed=%v notAllowed=%v", allowedVotes, notAllowedVotes)
+	}
+}
+
+func TestMergeBacklogMatchesCatalogNamesBySingularForm(t *testing.T) {
+	foods := loadTestCatalog(t, "Avocados\n")
+	allowedVotes := map[string]int{}
+	notAllowedVotes := map[string]int{"avocado": 1, "avocados": 2}
+
+	items := mergeBacklog(nil, foods, allowedVotes, notAllowedVotes, nil, foodcatalog.DefaultMatchOptions())
+	if len(items) != 1 {
+		t.Fatalf("expected one merged avocado item, got %#v", items)
+	}
+	if items[0].Text != "avocado" || !items[0].Contradiction || items[0].NotAllowedVotes != 3 {
+		t.Fatalf("expected avocado to contradict the catalog, got %#v", items[0])
+	}
+}
+
+func TestReadBacklogFeedbackSkipsHandledRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feedback.jsonl")
+	records := `{"subject":"Ghee","message":"is ghee ok?","status":"new"}
+{"subject":"Lard","message":"add lard","status":"handled"}
+{"subject":"Tigernut","message":"add tigernut"}
+`
+	if err := os.WriteFile(path, []byte(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := readBacklogFeedback(path)
+	if err != nil || len(messages) != 2 || messages[0] != "ghee is ghee ok?" || messages[1] != "tigernut add tigernut" {
+		t.Fatalf("unexpected messages %q err=%v", messages, err)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+const (
+	changeGained  = "gained"
+	changeLost    = "lost"
+	changeChanged = "changed"
+)
+
+// catalogDiff reports how a catalog edit changes results for logged searches.
+type catalogDiff struct {
+	OldCatalog string          `json:"oldCatalog"`
+	NewCatalog string          `json:"newCatalog"`
+	SearchKeys int             `json:"searchKeys"`
+	Requests   int             `json:"requests"`
+	Totals     map[string]int  `json:"totals"`
+	Weighted   map[string]int  `json:"weighted"`
+	Changes    []catalogChange `json:"changes"`
+}
+
+type catalogChange struct {
+	Key           string   `json:"key"`
+	Count         int      `json:"count"`
+	Change        string   `json:"change"`
+	OldAllowed    []string `json:"oldAllowed"`
+	OldNotAllowed []string `json:"oldNotAllowed"`
+	NewAllowed    []string `json:"newAllowed"`
+	NewNotAllowed []string `json:"newNotAllowed"`
+}
+
+func diffCatalogs(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	input := flags.String("input", "searches.tsv", "TSV, JSON or CSV exported by extract mode")
+	oldCatalog := flags.String("catalog-old", "", "data directory before the change")
+	newCatalog := flags.String("catalog-new", "../../data", "data directory after the change")
+	options := matchOptionFlags(flags)
+	format := flags.String("format", formatText, "output format: text or json")
+	output := flags.String("output", "-", "output path, or - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatText, formatJSON); err != nil {
+		return err
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	if *oldCatalog == "" {
+		return fmt.Errorf("--catalog-old is required")
+	}
+	searches, err := readSearchExport(*input)
+	if err != nil {
+		return err
+	}
+	oldFoods, err := foodcatalog.Load(*oldCatalog)
+	if err != nil {
+		return fmt.Errorf("load old catalog: %w", err)
+	}
+	newFoods, err := foodcatalog.Load(*newCatalog)
+	if err != nil {
+		return fmt.Errorf("load new catalog: %w", err)
+	}
+	diff := buildCatalogDiff(*oldCatalog, *newCatalog, searches, oldFoods, newFoods, *options)
+	return writeOutput(*output, func(writer io.Writer) error {
+		return writeCatalogDiff(writer, *format, diff)
+	})
+}
+
+// buildCatalogDiff replays every logged key through both catalogs, listing
+// the most searched changes first.
+func buildCatalogDiff(oldCatalog string, newCatalog string, searches map[string]loggedSearch, oldFoods []foodcatalog.Food, newFoods []foodcatalog.Food, options foodcatalog.MatchOptions) catalogDiff {
+	diff := catalogDiff{
+		OldCatalog: oldCatalog,
+		NewCatalog: newCatalog,
+		SearchKeys: len(searches),
+		Totals:     map[string]int{changeGained: 0, changeLost: 0, changeChanged: 0},
+		Weighted:   map[string]int{changeGained: 0, changeLost: 0, changeChanged: 0},
+		Changes:    []catalogChange{},
+	}
+	for _, key := range sortedKeys(searches) {
+		count := searches[key].Count
+		diff.Requests += count
+		before := options.Match(oldFoods, key, "searchbytextandsound")
+		after := options.Match(newFoods, key, "searchbytextandsound")
+		change := classifyChange(before, after)
+		if change == "" {
+			continue
+		}
+		diff.Totals[change]++
+		diff.Weighted[change] += count
+		diff.Changes = append(diff.Changes, catalogChange{
+			Key:           key,
+			Count:         count,
+			Change:        change,
+			OldAllowed:    nonNil(before.Allowed),
+			OldNotAllowed: nonNil(before.NotAllowed),
+			NewAllowed:    nonNil(after.Allowed),
+			NewNotAllowed: nonNil(after.NotAllowed),
+		})
+	}
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		return diff.Changes[i].Count > diff.Changes[j].Count
+	})
+	return diff
+}
+
+func classifyChange(before foodcatalog.Result, after foodcatalog.Result) string {
+	oldCovered := len(before.Allowed) > 0 || len(before.NotAllowed) > 0
+	newCovered := len(after.Allowed) > 0 || len(after.NotAllowed) > 0
+	switch {
+	case !oldCovered && newCovered:
+		return changeGained
+	case oldCovered && !newCovered:
+		return changeLost
+	case !sameStrings(before.Allowed, after.Allowed) || !sameStrings(before.NotAllowed, after.NotAllowed):
+		return changeChanged
+	default:
+		return ""
+	}
+}
+
+func sameStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func writeCatalogDiff(writer io.Writer, format string, diff catalogDiff) error {
+	switch format {
+	case formatText:
+		fmt.Fprintf(writer, "Old catalog: %s\nNew catalog: %s\nSearch keys: %d (%d requests)\n", diff.OldCatalog, diff.NewCatalog, diff.SearchKeys, diff.Requests)
+		for _, change := range []string{changeGained, changeLost, changeChanged} {
+			fmt.Fprintf(writer, "%-8s %d keys, %d requests\n", change+":", diff.Totals[change], diff.Weighted[change])
+		}
+		fmt.Fprintln(writer)
+		fmt.Fprintln(writer, "count | change | key | old | new")
+		fmt.Fprintln(writer, "------|--------|-----|-----|----")
+		for _, change := range diff.Changes {
+			fmt.Fprintf(writer, "%5d | %s | %s | %s | %s\n", change.Count, change.Change, change.Key,
+				formatDiffResult(change.OldAllowed, change.OldNotAllowed), formatDiffResult(change.NewAllowed, change.NewNotAllowed))
+		}
+		return nil
+	case formatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	default:
+		return fmt.Errorf("unknown format %q; use text or json", format)
+	}
+}
+
+func formatDiffResult(allowed []string, notAllowed []string) string {
+	if len(allowed) == 0 && len(notAllowed) == 0 {
+		return "none"
+	}
+	return formatMatchedFoods(coverageRow{Allowed: allowed, NotAllowed: notAllowed})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+func TestBuildCatalogDiffClassifiesChangesByWeight(t *testing.T) {
+	oldFoods := loadTestCatalog(t, "Pork\nWheat Bread\n")
+	newFoods := loadTestCatalog(t, "Pork\nPorchetta\nAgave\n")
+	searches := map[string]loggedSearch{
+		"agave":  {Count: 7},
+		"bread":  {Count: 1},
+		"porc":   {Count: 4},
+		"pork":   {Count: 20},
+		"wheat":  {Count: 3},
+		"qqqqqq": {Count: 9},
+	}
+
+	diff := buildCatalogDiff("old", "new", searches, oldFoods, newFoods, foodcatalog.DefaultMatchOptions())
+	if diff.Totals[changeGained] != 1 || diff.Weighted[changeGained] != 7 {
+		t.Fatalf("unexpected gained totals: %#v %#v", diff.Totals, diff.Weighted)
+	}
+	if diff.Totals[changeLost] != 2 || diff.Weighted[changeLost] != 4 {
+		t.Fatalf("unexpected lost totals: %#v %#v", diff.Totals, diff.Weighted)
+	}
+	if diff.Totals[changeChanged] != 1 || diff.Changes[1].Key != "porc" || len(diff.Changes[1].NewAllowed) != 2 {
+		t.Fatalf("unexpected changed results: %#v", diff.Changes)
+	}
+	if diff.Changes[0].Key != "agave" || diff.Changes[0].Change != changeGained {
+		t.Fatalf("expected most searched change first, got %#v", diff.Changes[0])
+	}
+
+	var buffer bytes.Buffer
+	if err := writeCatalogDiff(&buffer, formatText, diff); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "    3 | lost | wheat | allowed: Wheat Bread | none") {
+		t.Fatalf("unexpected text diff: %s", buffer.String())
+	}
+}
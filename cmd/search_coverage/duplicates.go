@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+// duplicateGroup is a set of catalog foods whose names only differ by case,
+// accents or plural form. The API keeps the first one loaded.
+type duplicateGroup struct {
+	Key   string          `json:"key"`
+	Foods []duplicateFood `json:"foods"`
+}
+
+type duplicateFood struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Source string `json:"source"`
+}
+
+func findDuplicates(args []string) error {
+	flags := flag.NewFlagSet("duplicates", flag.ContinueOnError)
+	catalog := flags.String("catalog", "../../data", "local repository data directory")
+	format := flags.String("format", formatText, "output format: text or json")
+	output := flags.String("output", "-", "output path, or - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatText, formatJSON); err != nil {
+		return err
+	}
+	foods, err := foodcatalog.Load(*catalog)
+	if err != nil {
+		return fmt.Errorf("load local catalog: %w", err)
+	}
+	groups := buildDuplicateGroups(*catalog, foods)
+	return writeOutput(*output, func(writer io.Writer) error {
+		return writeDuplicateGroups(writer, *format, groups)
+	})
+}
+
+func buildDuplicateGroups(catalog string, foods []foodcatalog.Food) []duplicateGroup {
+	groups := []duplicateGroup{}
+	for _, duplicates := range foodcatalog.Duplicates(foods) {
+		group := duplicateGroup{Key: foodcatalog.Singular(duplicates[0].Name)}
+		for _, food := range duplicates {
+			source := food.Source
+			if relative, err := filepath.Rel(catalog, food.Source); err == nil {
+				source = filepath.ToSlash(relative)
+			}
+			status := catalogStatusNotAllowed
+			if food.Allowed {
+				status = catalogStatusAllowed
+			}
+			group.Foods = append(group.Foods, duplicateFood{Name: food.Name, Status: status, Source: source})
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func writeDuplicateGroups(writer io.Writer, format string, groups []duplicateGroup) error {
+	switch format {
+	case formatText:
+		fmt.Fprintf(writer, "%d duplicate names\n", len(groups))
+		for _, group := range groups {
+			var foods []string
+			for _, food := range group.Foods {
+				foods = append(foods, fmt.Sprintf("%s (%s, %s)", food.Name, food.Status, food.Source))
+			}
+			fmt.Fprintf(writer, "%s: %s\n", group.Key, strings.Join(foods, "; "))
+		}
+		return nil
+	case formatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(groups)
+	default:
+		return fmt.Errorf("unknown format %q; use text or json", format)
+	}
+}
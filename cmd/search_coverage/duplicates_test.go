@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBuildDuplicateGroupsFindsPluralAndAccentVariants(t *testing.T) {
+	foods := loadTestCatalog(t, "Cherries\nPork\nCherry\nCrème Fraîche\nCREME FRAICHE\tsour cream\n")
+
+	groups := buildDuplicateGroups("", foods)
+	if len(groups) != 2 {
+		t.Fatalf("expected two duplicate groups, got %#v", groups)
+	}
+	if groups[0].Key != "cherry" || len(groups[0].Foods) != 2 || groups[0].Foods[0].Name != "Cherries" || groups[0].Foods[1].Name != "Cherry" {
+		t.Fatalf("unexpected cherry group: %#v", groups[0])
+	}
+	if groups[1].Key != "creme fraiche" || groups[1].Foods[0].Status != catalogStatusAllowed {
+		t.Fatalf("unexpected creme fraiche group: %#v", groups[1])
+	}
+
+	var buffer bytes.Buffer
+	if err := writeDuplicateGroups(&buffer, formatText, groups); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "2 duplicate names") || !strings.Contains(buffer.String(), "cherry: Cherries (allowed, ") {
+		t.Fatalf("unexpected text output: %s", buffer.String())
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown"
+	formatTSV      = "tsv"
+
+	matchTypeText  = "text"
+	matchTypeSound = "sound"
+	matchTypeNone  = "none"
+)
+
+// checkFormat rejects an unsupported --format before any output file is
+// created, so a typo does not leave an empty or truncated file behind.
+func checkFormat(format string, formats ...string) error {
+	for _, supported := range formats {
+		if format == supported {
+			return nil
+		}
+	}
+	choices := strings.Join(formats[:len(formats)-1], ", ") + " or " + formats[len(formats)-1]
+	return fmt.Errorf("unknown format %q; use %s", format, choices)
+}
+
+// coverageReport is the check mode result shared by every output format.
+type coverageReport struct {
+	Catalog    string        `json:"catalog"`
+	SearchKeys int           `json:"searchKeys"`
+	Covered    int           `json:"covered"`
+	Uncovered  int           `json:"uncovered"`
+	Searches   []coverageRow `json:"searches"`
+}
+
+type coverageRow struct {
+	Key        string      `json:"key"`
+	Count      int         `json:"count"`
+	Covered    bool        `json:"covered"`
+	MatchType  string      `json:"matchType"`
+	Allowed    []string    `json:"allowed"`
+	NotAllowed []string    `json:"notAllowed"`
+	Statuses   map[int]int `json:"statuses"`
+	FirstSeen  string      `json:"firstSeen"`
+	LastSeen   string      `json:"lastSeen"`
+}
+
+// exportRow is the JSON shape of one extract mode search key.
+type exportRow struct {
+	Key         string                  `json:"key"`
+	Count       int                     `json:"count"`
+	FirstSeen   string                  `json:"firstSeen"`
+	LastSeen    string                  `json:"lastSeen"`
+	Statuses    map[int]int             `json:"statuses"`
+	ZeroResults int                     `json:"zeroResults"`
+	Clients     map[string]int          `json:"clients"`
+	Periods     map[string]searchPeriod `json:"periods"`
+}
+
+var exportColumns = []string{"key", "count", "first_seen", "last_seen", "statuses", "zero_results", "clients", "periods"}
+
+func buildCoverageReport(catalog string, searches map[string]loggedSearch, foods []foodcatalog.Food, options foodcatalog.MatchOptions) coverageReport {
+	report := coverageReport{Catalog: catalog, SearchKeys: len(searches), Searches: []coverageRow{}}
+	for _, key := range sortedKeys(searches) {
+		search := searches[key]
+		row := coverageRow{
+			Key:        key,
+			Count:      search.Count,
+			MatchType:  matchTypeNone,
+			Allowed:    []string{},
+			NotAllowed: []string{},
+			Statuses:   search.Statuses,
+			FirstSeen:  search.FirstSeen,
+			LastSeen:   search.LastSeen,
+		}
+		for _, matched := range options.MatchDetails(foods, key, "searchbytextandsound") {
+			if matched.Allowed {
+				row.Allowed = appendUnique(row.Allowed, matched.Name)
+			} else {
+				row.NotAllowed = appendUnique(row.NotAllowed, matched.Name)
+			}
+			if matched.Text {
+				row.MatchType = matchTypeText
+			} else if row.MatchType == matchTypeNone {
+				row.MatchType = matchTypeSound
+			}
+		}
+		row.Covered = row.MatchType != matchTypeNone
+		if row.Covered {
+			report.Covered++
+		}
+		report.Searches = append(report.Searches, row)
+	}
+	report.Uncovered = report.SearchKeys - report.Covered
+	return report
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func writeCoverageReport(writer io.Writer, format string, report coverageReport) error {
+	switch format {
+	case formatText:
+		return writeCoverageText(writer, report)
+	case formatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case formatCSV:
+		return writeCoverageCSV(writer, report)
+	case formatMarkdown:
+		return writeCoverageMarkdown(writer, report)
+	default:
+		return fmt.Errorf("unknown format %q; use text, json, csv or markdown", format)
+	}
+}
+
+func writeCoverageText(writer io.Writer, report coverageReport) error {
+	fmt.Fprintf(writer, "Local catalog: %s\nSearch keys:   %d\nCovered:       %d\nUncovered:     %d\n\n", report.Catalog, report.SearchKeys, report.Covered, report.Uncovered)
+	fmt.Fprintln(writer, "UNCOVERED SEARCHES")
+	fmt.Fprintln(writer, "count | key | statuses | first seen | last seen")
+	fmt.Fprintln(writer, "------|-----|----------|------------|----------")
+	for _, row := range report.Searches {
+		if !row.Covered {
+			fmt.Fprintf(writer, "%5d | %s | %s | %s | %s\n", row.Count, row.Key, formatStatuses(row.Statuses), row.FirstSeen, row.LastSeen)
+		}
+	}
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "COVERED SEARCHES")
+	fmt.Fprintln(writer, "count | key | match | foods")
+	fmt.Fprintln(writer, "------|-----|-------|------")
+	for _, row := range report.Searches {
+		if row.Covered {
+			fmt.Fprintf(writer, "%5d | %s | %s | %s\n", row.Count, row.Key, row.MatchType, formatMatchedFoods(row))
+		}
+	}
+	return nil
+}
+
+func writeCoverageCSV(writer io.Writer, report coverageReport) error {
+	csvWriter := csv.NewWriter(writer)
+	_ = csvWriter.Write([]string{"key", "count", "covered", "match_type", "allowed", "not_allowed", "statuses", "first_seen", "last_seen"})
+	for _, row := range report.Searches {
+		_ = csvWriter.Write([]string{
+			row.Key,
+			strconv.Itoa(row.Count),
+			strconv.FormatBool(row.Covered),
+			row.MatchType,
+			strings.Join(row.Allowed, "; "),
+			strings.Join(row.NotAllowed, "; "),
+			formatStatuses(row.Statuses),
+			row.FirstSeen,
+			row.LastSeen,
+		})
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func writeCoverageMarkdown(writer io.Writer, report coverageReport) error {
+	fmt.Fprintf(writer, "# Search coverage\n\n| Catalog | Search keys | Covered | Uncovered |\n| --- | ---: | ---: | ---: |\n| %s | %d | %d | %d |\n\n",
+		escapeMarkdownCell(report.Catalog), report.SearchKeys, report.Covered, report.Uncovered)
+	fmt.Fprintln(writer, "## Uncovered searches")
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "| Count | Key | Statuses | First seen | Last seen |")
+	fmt.Fprintln(writer, "| ---: | --- | --- | --- | --- |")
+	for _, row := range report.Searches {
+		if !row.Covered {
+			fmt.Fprintf(writer, "| %d | %s | %s | %s | %s |\n", row.Count, escapeMarkdownCell(row.Key), formatStatuses(row.Statuses), row.FirstSeen, row.LastSeen)
+		}
+	}
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "## Covered searches")
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "| Count | Key | Match | Foods |")
+	fmt.Fprintln(writer, "| ---: | --- | --- | --- |")
+	for _, row := range report.Searches {
+		if row.Covered {
+			fmt.Fprintf(writer, "| %d | %s | %s | %s |\n", row.Count, escapeMarkdownCell(row.Key), row.MatchType, escapeMarkdownCell(formatMatchedFoods(row)))
+		}
+	}
+	return nil
+}
+
+func formatMatchedFoods(row coverageRow) string {
+	parts := []string{}
+	if len(row.Allowed) > 0 {
+		parts = append(parts, "allowed: "+strings.Join(row.Allowed, ", "))
+	}
+	if len(row.NotAllowed) > 0 {
+		parts = append(parts, "not allowed: "+strings.Join(row.NotAllowed, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func escapeMarkdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", "\\|")
+}
+
+// writeSearchExport writes extract mode output as TSV, JSON or CSV.
+func writeSearchExport(writer io.Writer, format string, searches map[string]loggedSearch) error {
+	switch format {
+	case formatTSV:
+		fmt.Fprintln(writer, "# AIP Food Lookup search coverage export")
+		fmt.Fprintln(writer, "# "+strings.Join(exportColumns, "\t"))
+		for _, key := range sortedKeys(searches) {
+			fmt.Fprintln(writer, strings.Join(exportFields(key, searches[key]), "\t"))
+		}
+		return nil
+	case formatJSON:
+		rows := []exportRow{}
+		for _, key := range sortedKeys(searches) {
+			search := searches[key]
+			rows = append(rows, exportRow{Key: key, Count: search.Count, FirstSeen: search.FirstSeen, LastSeen: search.LastSeen, Statuses: search.Statuses, ZeroResults: search.ZeroResults, Clients: search.Clients, Periods: search.Periods})
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	case formatCSV:
+		csvWriter := csv.NewWriter(writer)
+		_ = csvWriter.Write(exportColumns)
+		for _, key := range sortedKeys(searches) {
+			_ = csvWriter.Write(exportFields(key, searches[key]))
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	default:
+		return fmt.Errorf("unknown format %q; use tsv, json or csv", format)
+	}
+}
+
+func exportFields(key string, search loggedSearch) []string {
+	return []string{key, strconv.Itoa(search.Count), search.FirstSeen, search.LastSeen, formatStatuses(search.Statuses), strconv.Itoa(search.ZeroResults), formatClients(search.Clients), formatPeriods(search.Periods)}
+}
+
+// readSearchExport loads an extract mode export, choosing the parser from
+// the file extension so JSON and CSV exports can be checked directly.
+func readSearchExport(path string) (map[string]loggedSearch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open search export %s: %w", path, err)
+	}
+	defer file.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var rows []exportRow
+		if err := json.NewDecoder(file).Decode(&rows); err != nil {
+			return nil, fmt.Errorf("decode search export %s: %w", path, err)
+		}
+		searches := make(map[string]loggedSearch)
+		for _, row := range rows {
+			search := loggedSearch{Count: row.Count, FirstSeen: row.FirstSeen, LastSeen: row.LastSeen, Statuses: row.Statuses, ZeroResults: row.ZeroResults, Clients: row.Clients, Periods: row.Periods}
+			if search.Statuses == nil {
+				search.Statuses = make(map[int]int)
+			}
+			if search.Clients == nil {
+				search.Clients = make(map[string]int)
+			}
+			if search.Periods == nil {
+				search.Periods = make(map[string]searchPeriod)
+			}
+			searches[row.Key] = search
+		}
+		return searches, nil
+	case ".csv":
+		records, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("read search export %s: %w", path, err)
+		}
+		searches := make(map[string]loggedSearch)
+		for index, record := range records {
+			if index == 0 && len(record) > 0 && record[0] == "key" {
+				continue
+			}
+			if err := parseExportFields(record, searches); err != nil {
+				return nil, err
+			}
+		}
+		return searches, nil
+	default:
+		return readSearchExportTSV(file)
+	}
+}
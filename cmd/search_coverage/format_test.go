@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+func TestBuildCoverageReportIncludesMatchedFoodsAndMatchType(t *testing.T) {
+	foods := loadTestCatalog(t, "Pork\nPorchetta\n")
+	searches := map[string]loggedSearch{
+		"porc":  {Count: 3, Statuses: map[int]int{200: 3}},
+		"porch": {Count: 2, Statuses: map[int]int{200: 2}},
+		"qqqq":  {Count: 1, Statuses: map[int]int{200: 1}},
+	}
+
+	report := buildCoverageReport("catalog", searches, foods, foodcatalog.DefaultMatchOptions())
+	if report.Covered != 2 || report.Uncovered != 1 {
+		t.Fatalf("unexpected totals: %#v", report)
+	}
+	rows := map[string]coverageRow{}
+	for _, row := range report.Searches {
+		rows[row.Key] = row
+	}
+	if rows["porch"].MatchType != matchTypeText || rows["porc"].MatchType != matchTypeText || rows["qqqq"].MatchType != matchTypeNone {
+		t.Fatalf("unexpected match types: %#v", rows)
+	}
+	if len(rows["porc"].Allowed) != 2 || rows["porc"].Allowed[0] != "Pork" {
+		t.Fatalf("expected matched foods for porc, got %#v", rows["porc"])
+	}
+	if coverage := weightedCoverage(report); coverage < 83 || coverage > 84 {
+		t.Fatalf("unexpected weighted coverage %.2f", coverage)
+	}
+}
+
+func TestWriteCoverageReportFormats(t *testing.T) {
+	report := buildCoverageReport("catalog", map[string]loggedSearch{
+		"pork": {Count: 4, Statuses: map[int]int{200: 4}},
+		"a|b":  {Count: 1, Statuses: map[int]int{200: 1}},
+	}, loadTestCatalog(t, "Pork\n"), foodcatalog.DefaultMatchOptions())
+
+	var buffer bytes.Buffer
+	if err := writeCoverageReport(&buffer, formatJSON, report); err != nil {
+		t.Fatal(err)
+	}
+	var decoded coverageReport
+	if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil || decoded.Covered != 1 || decoded.Searches[1].Allowed[0] != "Pork" {
+		t.Fatalf("unexpected JSON report: %s err=%v", buffer.String(), err)
+	}
+
+	buffer.Reset()
+	if err := writeCoverageReport(&buffer, formatCSV, report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "pork,4,true,text,Pork,,200:4") {
+		t.Fatalf("unexpected CSV report: %s", buffer.String())
+	}
+
+	buffer.Reset()
+	if err := writeCoverageReport(&buffer, formatMarkdown, report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "| 1 | a\\|b |") || !strings.Contains(buffer.String(), "| 4 | pork | text | allowed: Pork |") {
+		t.Fatalf("unexpected Markdown report: %s", buffer.String())
+	}
+
+	if err := writeCoverageReport(&buffer, "xml", report); err == nil {
+		t.Fatal("expected unknown format error")
+	}
+}
+
+func TestSearchExportRoundTripsThroughJSONAndCSV(t *testing.T) {
+	searches := map[string]loggedSearch{
+		"aga": {Count: 2, FirstSeen: "a", LastSeen: "b", Statuses: map[int]int{200: 2}, ZeroResults: 1, Clients: map[string]int{"android": 2}},
+	}
+	directory := t.TempDir()
+	for _, format := range []string{formatTSV, formatJSON, formatCSV} {
+		var buffer bytes.Buffer
+		if err := writeSearchExport(&buffer, format, searches); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(directory, "searches."+format)
+		if err := os.WriteFile(path, buffer.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := readSearchExport(path)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if loaded["aga"].Count != 2 || loaded["aga"].ZeroResults != 1 || loaded["aga"].Clients["android"] != 2 {
+			t.Fatalf("%s: unexpected round trip %#v", format, loaded)
+		}
+	}
+}
+
+func loadTestCatalog(t *testing.T, allowedFoods string) []foodcatalog.Food {
+	t.Helper()
+
+	directory := t.TempDir()
+	if err := os.Mkdir(filepath.Join(directory, "allowed"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, "allowed", "meats.dat"), []byte(allowedFoods), 0644); err != nil {
+		t.Fatal(err)
+	}
+	foods, err := foodcatalog.Load(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return foods
+}
+
+func TestUnknownFormatLeavesNoOutputFile(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "report.txt")
+
+	err := runCLI([]string{"check", "--input", "missing.tsv", "--format", "yaml", "--output", output})
+	if err == nil || err.Error() != `unknown format "yaml"; use text, json, csv or markdown` {
+		t.Fatalf("expected an unknown format error, got %v", err)
+	}
+	if _, statErr := os.Stat(output); !os.IsNotExist(statErr) {
+		t.Fatalf("expected no output file, got %v", statErr)
+	}
+}
+
+func TestCheckUsesMatchOptionFlags(t *testing.T) {
+	tempDir := t.TempDir()
+	foods := loadTestCatalog(t, "Pork\n")
+	catalog := filepath.Dir(filepath.Dir(foods[0].Source))
+	searches := filepath.Join(tempDir, "searches.tsv")
+	if err := os.WriteFile(searches, []byte("porc\t3\t\t\t200:3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(tempDir, "report.json")
+
+	for _, test := range []struct {
+		distance string
+		covered  int
+	}{{"1", 1}, {"0", 0}} {
+		err := runCLI([]string{"check", "--input", searches, "--catalog", catalog, "--format", "json", "--output", output, "--short-distance", test.distance})
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := os.ReadFile(output)
+		var report coverageReport
+		if err := json.Unmarshal(content, &report); err != nil || report.Covered != test.covered {
+			t.Fatalf("--short-distance %s: covered %d, want %d (err %v)", test.distance, report.Covered, test.covered, err)
+		}
+	}
+
+	err := runCLI([]string{"check", "--input", searches, "--catalog", catalog, "--output", output, "--min-token-length", "0"})
+	if err == nil || !strings.Contains(err.Error(), "minTokenLength") {
+		t.Fatalf("expected invalid thresholds to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+// goldenOutcome is one golden query's check or accept result. Problems lists
+// how the current results differ from the golden file.
+type goldenOutcome struct {
+	Query    string   `json:"query"`
+	Type     string   `json:"type,omitempty"`
+	Lang     string   `json:"lang,omitempty"`
+	Changed  bool     `json:"changed"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+func checkGoldenQueries(args []string) error {
+	flags := flag.NewFlagSet("golden", flag.ContinueOnError)
+	golden := flags.String("golden", "../../internal/foodcatalog/testdata/golden_queries.yaml", "golden query file, YAML or JSON")
+	catalog := flags.String("catalog", "../../data", "local repository data directory")
+	accept := flags.Bool("accept", false, "rewrite the golden file with the current results after an intentional matcher or catalog change")
+	format := flags.String("format", formatText, "output format: text or json")
+	output := flags.String("output", "-", "output path, or - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatText, formatJSON); err != nil {
+		return err
+	}
+	queries, err := foodcatalog.LoadGoldenQueries(*golden)
+	if err != nil {
+		return err
+	}
+	foods, err := foodcatalog.Load(*catalog)
+	if err != nil {
+		return fmt.Errorf("load local catalog: %w", err)
+	}
+
+	outcomes := runGoldenQueries(foods, queries)
+	if *accept {
+		accepted, err := acceptGoldenQueries(foods, queries)
+		if err != nil {
+			return err
+		}
+		if err := foodcatalog.WriteGoldenQueries(*golden, accepted); err != nil {
+			return fmt.Errorf("write golden queries %s: %w", *golden, err)
+		}
+	}
+	if err := writeOutput(*output, func(writer io.Writer) error {
+		return writeGoldenOutcomes(writer, *format, *accept, outcomes)
+	}); err != nil {
+		return err
+	}
+	if changed := countChangedGoldens(outcomes); changed > 0 && !*accept {
+		return fmt.Errorf("%d of %d golden queries changed; rerun with --accept if the changes are intended", changed, len(outcomes))
+	}
+	return nil
+}
+
+func runGoldenQueries(foods []foodcatalog.Food, queries []foodcatalog.GoldenQuery) []goldenOutcome {
+	options := foodcatalog.DefaultMatchOptions()
+	outcomes := make([]goldenOutcome, 0, len(queries))
+	for _, query := range queries {
+		problems := options.CheckGolden(foods, query)
+		outcomes = append(outcomes, goldenOutcome{Query: query.Query, Type: query.Type, Lang: query.Lang, Changed: len(problems) > 0, Problems: problems})
+	}
+	return outcomes
+}
+
+// acceptGoldenQueries replaces every query's expected lists with the current
+// results, refusing all of them if any returns a must-not-include food.
+func acceptGoldenQueries(foods []foodcatalog.Food, queries []foodcatalog.GoldenQuery) ([]foodcatalog.GoldenQuery, error) {
+	options := foodcatalog.DefaultMatchOptions()
+	accepted := make([]foodcatalog.GoldenQuery, 0, len(queries))
+	var refused []string
+	for _, query := range queries {
+		updated, err := options.AcceptGolden(foods, query)
+		if err != nil {
+			refused = append(refused, err.Error())
+		}
+		accepted = append(accepted, updated)
+	}
+	if len(refused) > 0 {
+		return nil, fmt.Errorf("golden file not updated:\n%s", strings.Join(refused, "\n"))
+	}
+	return accepted, nil
+}
+
+func countChangedGoldens(outcomes []goldenOutcome) int {
+	changed := 0
+	for _, outcome := range outcomes {
+		if outcome.Changed {
+			changed++
+		}
+	}
+	return changed
+}
+
+func writeGoldenOutcomes(writer io.Writer, format string, accepted bool, outcomes []goldenOutcome) error {
+	switch format {
+	case formatText:
+		verb := "changed"
+		if accepted {
+			verb = "accepted"
+		}
+		fmt.Fprintf(writer, "Golden queries: %d, %s: %d\n", len(outcomes), verb, countChangedGoldens(outcomes))
+		for _, outcome := range outcomes {
+			if !outcome.Changed {
+				continue
+			}
+			label := outcome.Query
+			if outcome.Type != "" || outcome.Lang != "" {
+				label = fmt.Sprintf("%s (%s)", outcome.Query, strings.Trim(outcome.Type+" "+outcome.Lang, " "))
+			}
+			fmt.Fprintf(writer, "\n%s\n", label)
+			for _, problem := range outcome.Problems {
+				fmt.Fprintf(writer, "  %s\n", problem)
+			}
+		}
+		return nil
+	case formatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(outcomes)
+	default:
+		return fmt.Errorf("unknown format %q; use text or json", format)
+	}
+}
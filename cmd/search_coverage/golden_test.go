@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+func TestGoldenModeFailsOnChangesUntilAccepted(t *testing.T) {
+	tempDir := t.TempDir()
+	foods := loadTestCatalog(t, "Pork\nPorchetta\nBeef\n")
+	catalog := filepath.Dir(filepath.Dir(foods[0].Source))
+	golden := filepath.Join(tempDir, "golden.yaml")
+	if err := os.WriteFile(golden, []byte("- query: porc\n  allowed: [Pork]\n  must_not_include: [Beef]\n- query: beef\n  allowed: [Beef]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(tempDir, "golden.txt")
+
+	err := runCLI([]string{"golden", "--golden", golden, "--catalog", catalog, "--output", output})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 golden queries changed") {
+		t.Fatalf("expected the porc change to fail the check, got %v", err)
+	}
+	report, _ := os.ReadFile(output)
+	if !strings.Contains(string(report), "porc\n  allowed = [Porchetta, Pork], want [Pork]") {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+
+	if err := runCLI([]string{"golden", "--golden", golden, "--catalog", catalog, "--accept", "--output", output}); err != nil {
+		t.Fatal(err)
+	}
+	queries, err := foodcatalog.LoadGoldenQueries(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries[0].Allowed) != 2 || len(queries[0].MustNotInclude) != 1 {
+		t.Fatalf("unexpected accepted golden query: %#v", queries[0])
+	}
+	if err := runCLI([]string{"golden", "--golden", golden, "--catalog", catalog, "--output", output}); err != nil {
+		t.Fatalf("expected accepted goldens to pass, got %v", err)
+	}
+}
+
+func TestAcceptGoldenQueriesRefusesMustNotIncludeResults(t *testing.T) {
+	foods := loadTestCatalog(t, "Pork\nPorchetta\n")
+	queries := []foodcatalog.GoldenQuery{{Query: "pork"}, {Query: "porc", MustNotInclude: []string{"Porchetta"}}}
+
+	if _, err := acceptGoldenQueries(foods, queries); err == nil || !strings.Contains(err.Error(), `"porc": returned "Porchetta"`) {
+		t.Fatalf("expected porc to block accepting, got %v", err)
+	}
+}
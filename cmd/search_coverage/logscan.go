@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const stdinLogs = "-"
+
+var errInvalidTimestamp = errors.New("invalid timestamp")
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// logScanStats counts how every line read from the logs was classified so
+// format drift shows up as malformed lines instead of silently lost searches.
+// BadTimestamps is the part of Malformed whose time did not parse.
+type logScanStats struct {
+	Files         int
+	Lines         int
+	Searches      int
+	Other         int
+	Overlap       int
+	Malformed     int
+	BadTimestamps int
+}
+
+func (s *logScanStats) add(other logScanStats) {
+	s.Files += other.Files
+	s.Lines += other.Lines
+	s.Searches += other.Searches
+	s.Other += other.Other
+	s.Overlap += other.Overlap
+	s.Malformed += other.Malformed
+	s.BadTimestamps += other.BadTimestamps
+}
+
+func (s logScanStats) String() string {
+	return fmt.Sprintf("Read %d lines from %d files: %d searches, %d other requests, %d already covered by search events, %d malformed (%d with unparseable timestamps)",
+		s.Lines, s.Files, s.Searches, s.Other, s.Overlap, s.Malformed, s.BadTimestamps)
+}
+
+// logScan is the partial result of reading one log file or stream.
+type logScan struct {
+	Searches map[string]loggedSearch
+	Stats    logScanStats
+	Earliest time.Time
+
+	// deferred holds the access-log searches of a mixed stream until its
+	// earliest search event, and with it the cutoff, is known.
+	deferAccess bool
+	deferred    []accessLogEntry
+}
+
+// accessLogEntry is one request parsed from either access log format.
+type accessLogEntry struct {
+	Time   time.Time
+	Method string
+	Target string
+	Status int
+}
+
+// jsonLogLine decodes a JSONL search event or a JSON access log entry; access
+// entries are told apart by their method field.
+type jsonLogLine struct {
+	searchEvent
+	Method string `json:"method"`
+	URI    string `json:"uri"`
+	Path   string `json:"path"`
+	Query  string `json:"query"`
+	Status int    `json:"status"`
+}
+
+// collectLoggedSearches prefers JSONL search events and only counts access-log
+// searches from before the first event, so the two sources never overlap.
+// Rotated files are read by up to workers goroutines and merged in path order.
+func collectLoggedSearches(directory string, bucket string, workers int) (map[string]loggedSearch, logScanStats, error) {
+	if directory == stdinLogs {
+		scan, err := scanMixedLogStream(os.Stdin, bucket)
+		scan.Stats.Files = 1
+		return scan.Searches, scan.Stats, err
+	}
+	accessPaths, err := filepath.Glob(filepath.Join(directory, "access.log*"))
+	if err != nil {
+		return nil, logScanStats{}, err
+	}
+	searchPaths, err := filepath.Glob(filepath.Join(directory, "search.log*"))
+	if err != nil {
+		return nil, logScanStats{}, err
+	}
+	sort.Strings(accessPaths)
+	sort.Strings(searchPaths)
+
+	searches := make(map[string]loggedSearch)
+	var stats logScanStats
+	eventScans, err := scanLogFiles(searchPaths, bucket, time.Time{}, workers)
+	if err != nil {
+		return nil, stats, err
+	}
+	var cutoff time.Time
+	for _, scan := range eventScans {
+		mergeSearches(searches, scan.Searches)
+		stats.add(scan.Stats)
+		if !scan.Earliest.IsZero() && (cutoff.IsZero() || scan.Earliest.Before(cutoff)) {
+			cutoff = scan.Earliest
+		}
+	}
+	accessScans, err := scanLogFiles(accessPaths, bucket, cutoff, workers)
+	if err != nil {
+		return nil, stats, err
+	}
+	for _, scan := range accessScans {
+		mergeSearches(searches, scan.Searches)
+		stats.add(scan.Stats)
+	}
+	return searches, stats, nil
+}
+
+// scanLogFiles reads paths concurrently, returning results in path order.
+func scanLogFiles(paths []string, bucket string, cutoff time.Time, workers int) ([]logScan, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	scans := make([]logScan, len(paths))
+	errs := make([]error, len(paths))
+	indexes := make(chan int)
+	var wait sync.WaitGroup
+	for worker := 0; worker < workers && worker < len(paths); worker++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for index := range indexes {
+				scans[index], errs[index] = scanLogFile(paths[index], bucket, cutoff)
+			}
+		}()
+	}
+	for index := range paths {
+		indexes <- index
+	}
+	close(indexes)
+	wait.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scans, nil
+}
+
+func scanLogFile(path string, bucket string, cutoff time.Time) (logScan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return logScan{}, fmt.Errorf("open log %s: %w", path, err)
+	}
+	defer file.Close()
+	scan, err := scanLogStream(file, bucket, cutoff)
+	if err != nil {
+		return scan, fmt.Errorf("read log %s: %w", path, err)
+	}
+	scan.Stats.Files = 1
+	return scan, nil
+}
+
+// scanMixedLogStream reads a stream that may carry both search events and
+// access-log lines, such as stdin, and counts access-log searches only before
+// the stream's first event, like collectLoggedSearches does for a directory.
+func scanMixedLogStream(reader io.Reader, bucket string) (logScan, error) {
+	scan := logScan{Searches: make(map[string]loggedSearch), deferAccess: true}
+	err := scan.read(reader, bucket, time.Time{})
+	scan.deferAccess = false
+	for _, entry := range scan.deferred {
+		scan.recordAccessEntry(entry, bucket, scan.Earliest)
+	}
+	scan.deferred = nil
+	return scan, err
+}
+
+// scanLogStream classifies every line of a plain, gzip or zstd stream. Search
+// events are always counted; access-log searches only before cutoff.
+func scanLogStream(reader io.Reader, bucket string, cutoff time.Time) (logScan, error) {
+	scan := logScan{Searches: make(map[string]loggedSearch)}
+	err := scan.read(reader, bucket, cutoff)
+	return scan, err
+}
+
+func (scan *logScan) read(reader io.Reader, bucket string, cutoff time.Time) error {
+	decompressed, closeReader, err := openLogReader(reader)
+	if err != nil {
+		return err
+	}
+	defer closeReader()
+	scanner := newLogScanner(decompressed)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		scan.Stats.Lines++
+		if line[0] == '{' {
+			scan.recordJSONLine(line, bucket, cutoff)
+			continue
+		}
+		entry, err := parseAccessLogLine(string(line))
+		if err != nil {
+			scan.malformed(err)
+			continue
+		}
+		scan.recordAccessEntry(entry, bucket, cutoff)
+	}
+	return scanner.Err()
+}
+
+func (scan *logScan) recordJSONLine(line []byte, bucket string, cutoff time.Time) {
+	var record jsonLogLine
+	if err := json.Unmarshal(line, &record); err != nil {
+		scan.malformed(err)
+		return
+	}
+	if record.Method != "" {
+		target := record.URI
+		if target == "" {
+			target = record.Path
+			if record.Query != "" {
+				target += "?" + strings.TrimPrefix(record.Query, "?")
+			}
+		}
+		if record.Time.IsZero() {
+			scan.malformed(errInvalidTimestamp)
+			return
+		}
+		if target == "" || record.Status == 0 {
+			scan.Stats.Malformed++
+			return
+		}
+		scan.recordAccessEntry(accessLogEntry{Time: record.Time, Method: record.Method, Target: target, Status: record.Status}, bucket, cutoff)
+		return
+	}
+	event := record.searchEvent
+	key := strings.TrimSpace(event.Key)
+	if event.Time.IsZero() {
+		scan.malformed(errInvalidTimestamp)
+		return
+	}
+	if key == "" {
+		scan.Stats.Malformed++
+		return
+	}
+	if scan.Earliest.IsZero() || event.Time.Before(scan.Earliest) {
+		scan.Earliest = event.Time
+	}
+	search := recordSearch(scan.Searches, key, event.Time, 200, bucket)
+	zeroResults := 0
+	if event.Allowed == 0 && event.NotAllowed == 0 {
+		zeroResults = 1
+	}
+	search.ZeroResults += zeroResults
+	if period, ok := periodKey(event.Time, bucket); ok {
+		counts := search.Periods[period]
+		counts.Events++
+		counts.ZeroResults += zeroResults
+		search.Periods[period] = counts
+	}
+	if client := strings.TrimSpace(event.Client); client != "" && client != "-" {
+		search.Clients[client]++
+	}
+	scan.Searches[key] = search
+	scan.Stats.Searches++
+}
+
+// malformed counts a line that could not be used, noting unparseable
+// timestamps separately since they usually mean a changed log time format.
+func (scan *logScan) malformed(err error) {
+	scan.Stats.Malformed++
+	var parseErr *time.ParseError
+	if errors.Is(err, errInvalidTimestamp) || errors.As(err, &parseErr) {
+		scan.Stats.BadTimestamps++
+	}
+}
+
+// recordAccessEntry counts every key parameter of a /search request, so batch
+// calls passing several keys contribute one search each.
+func (scan *logScan) recordAccessEntry(entry accessLogEntry, bucket string, cutoff time.Time) {
+	target, err := url.ParseRequestURI(entry.Target)
+	if err != nil {
+		scan.Stats.Malformed++
+		return
+	}
+	if target.Path != "/search" || !isSearchMethod(entry.Method) {
+		scan.Stats.Other++
+		return
+	}
+	keys := []string{}
+	for _, key := range target.Query()["key"] {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		scan.Stats.Other++
+		return
+	}
+	if scan.deferAccess {
+		scan.deferred = append(scan.deferred, entry)
+		return
+	}
+	if !cutoff.IsZero() && !entry.Time.Before(cutoff) {
+		scan.Stats.Overlap += len(keys)
+		return
+	}
+	for _, key := range keys {
+		scan.Searches[key] = recordSearch(scan.Searches, key, entry.Time, entry.Status, bucket)
+		scan.Stats.Searches++
+	}
+}
+
+func isSearchMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "POST":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseAccessLogLine reads the accessLogMiddleware layout:
+//
+//	ip - - [time] "METHOD URI PROTO" status bytes "referer" "agent" 12ms
+//
+// Only the fields up to the byte count are required, so older lines without
+// a duration and proxies that append extra fields still parse.
+func parseAccessLogLine(line string) (accessLogEntry, error) {
+	var entry accessLogEntry
+	open := strings.Index(line, " [")
+	if open < 0 {
+		return entry, fmt.Errorf("missing timestamp")
+	}
+	closing := strings.Index(line[open:], "] ")
+	if closing < 0 {
+		return entry, fmt.Errorf("unterminated timestamp")
+	}
+	seen, err := time.Parse(accessLogTimeLayout, line[open+2:open+closing])
+	if err != nil {
+		return entry, fmt.Errorf("%w: %v", errInvalidTimestamp, err)
+	}
+	rest := line[open+closing+2:]
+	if !strings.HasPrefix(rest, `"`) {
+		return entry, fmt.Errorf("missing request")
+	}
+	end := strings.Index(rest[1:], `" `)
+	if end < 0 {
+		return entry, fmt.Errorf("unterminated request")
+	}
+	request := strings.Fields(rest[1 : end+1])
+	if len(request) < 3 {
+		return entry, fmt.Errorf("invalid request %q", rest[1:end+1])
+	}
+	fields := strings.Fields(rest[end+2:])
+	if len(fields) < 2 {
+		return entry, fmt.Errorf("missing status")
+	}
+	status, err := strconv.Atoi(fields[0])
+	if err != nil || status < 100 || status > 599 {
+		return entry, fmt.Errorf("invalid status %q", fields[0])
+	}
+	entry.Time = seen
+	entry.Method = request[0]
+	entry.Target = strings.Join(request[1:len(request)-1], " ")
+	entry.Status = status
+	return entry, nil
+}
+
+// openLogReader detects gzip and zstd by their magic bytes, so rotated files
+// and stdin are handled the same way regardless of file name.
+func openLogReader(reader io.Reader) (io.Reader, func(), error) {
+	buffered := bufio.NewReaderSize(reader, 64*1024)
+	header, _ := buffered.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		compressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open gzip log: %w", err)
+		}
+		return compressed, func() { compressed.Close() }, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		compressed, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open zstd log: %w", err)
+		}
+		return compressed, compressed.Close, nil
+	default:
+		return buffered, func() {}, nil
+	}
+}
+
+func newLogScanner(reader io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return scanner
+}
+
+// mergeSearches folds one file's partial results into searches.
+func mergeSearches(searches map[string]loggedSearch, partial map[string]loggedSearch) {
+	for key, other := range partial {
+		search, exists := searches[key]
+		if !exists {
+			searches[key] = other
+			continue
+		}
+		search.Count += other.Count
+		search.ZeroResults += other.ZeroResults
+		for status, count := range other.Statuses {
+			search.Statuses[status] += count
+		}
+		for client, count := range other.Clients {
+			search.Clients[client] += count
+		}
+		for period, counts := range other.Periods {
+			merged := search.Periods[period]
+			merged.Count += counts.Count
+			merged.Events += counts.Events
+			merged.ZeroResults += counts.ZeroResults
+			search.Periods[period] = merged
+		}
+		if seenBefore(other.FirstSeen, search.FirstSeen) {
+			search.FirstSeen = other.FirstSeen
+		}
+		if seenBefore(search.LastSeen, other.LastSeen) {
+			search.LastSeen = other.LastSeen
+		}
+		searches[key] = search
+	}
+}
+
+func seenBefore(a string, b string) bool {
+	first, err := time.Parse(accessLogTimeLayout, a)
+	if err != nil {
+		return false
+	}
+	second, err := time.Parse(accessLogTimeLayout, b)
+	return err != nil || first.Before(second)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestParseAccessLogLineAcceptsAnyMethodAndOldLines(t *testing.T) {
+	entry, err := parseAccessLogLine(`203.0.113.0 - - [18/Aug/2026:01:29:51 +0000] "HEAD /search?key=aga HTTP/2.0" 200 0 "-" "Dart/3.4 (dart:io)" 1ms`)
+	if err != nil || entry.Method != "HEAD" || entry.Target != "/search?key=aga" || entry.Status != 200 {
+		t.Fatalf("unexpected entry %+v err=%v", entry, err)
+	}
+	if _, err := parseAccessLogLine(`x - - [18/Aug/2026:01:29:51 +0000] "POST /search?key=a&key=b HTTP/1.1" 200 49`); err != nil {
+		t.Fatalf("expected line without referer and duration to parse, got %v", err)
+	}
+	for _, line := range []string{
+		`x - - 18/Aug/2026:01:29:51 +0000 "GET / HTTP/1.1" 200 1`,
+		`x - - [yesterday] "GET / HTTP/1.1" 200 1`,
+		`x - - [18/Aug/2026:01:29:51 +0000] "GET /" 200 1`,
+		`x - - [18/Aug/2026:01:29:51 +0000] "GET / HTTP/1.1" ok 1`,
+	} {
+		if _, err := parseAccessLogLine(line); err == nil {
+			t.Fatalf("expected %q to be malformed", line)
+		}
+	}
+	if _, err := parseAccessLogLine(`x - - [yesterday] "GET / HTTP/1.1" 200 1`); !errors.Is(err, errInvalidTimestamp) {
+		t.Fatalf("expected an invalid timestamp error, got %v", err)
+	}
+}
+
+func TestScanLogStreamCountsEveryLineKind(t *testing.T) {
+	lines := []string{
+		`x - - [18/Aug/2026:01:29:51 +0000] "GET /search?key=aga HTTP/1.1" 200 49 "-" "Dart" 1ms`,
+		`x - - [18/Aug/2026:01:29:52 +0000] "POST /search?key=agave&key=apple HTTP/1.1" 200 49 "-" "Dart" 1ms`,
+		`x - - [18/Aug/2026:01:29:53 +0000] "OPTIONS /search?key=aga HTTP/1.1" 204 0 "-" "-" 0ms`,
+		`x - - [18/Aug/2026:01:29:54 +0000] "GET /robots.txt HTTP/1.1" 404 19 "-" "-" 0ms`,
+		`{"time":"2026-08-18T01:29:55Z","method":"GET","path":"/search","query":"key=aga","status":429}`,
+		`{"time":"2026-08-18T01:29:56Z","key":"zzzz","allowed":0,"notAllowed":0,"client":"ios"}`,
+		`garbage`,
+		`{"time":"not a time"}`,
+	}
+	scan, err := scanLogStream(strings.NewReader(strings.Join(lines, "\n")+"\n"), bucketNone, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := logScanStats{Lines: 8, Searches: 5, Other: 2, Malformed: 2, BadTimestamps: 1}
+	if scan.Stats != want {
+		t.Fatalf("expected stats %+v, got %+v", want, scan.Stats)
+	}
+	if scan.Searches["aga"].Count != 2 || scan.Searches["aga"].Statuses[429] != 1 || scan.Searches["apple"].Count != 1 {
+		t.Fatalf("unexpected searches %#v", scan.Searches)
+	}
+	if scan.Searches["zzzz"].ZeroResults != 1 || !scan.Earliest.Equal(time.Date(2026, 8, 18, 1, 29, 56, 0, time.UTC)) {
+		t.Fatalf("expected search event to be recorded, got %#v earliest=%v", scan.Searches["zzzz"], scan.Earliest)
+	}
+}
+
+func TestCollectLoggedSearchesMergesZstdRotationsInParallel(t *testing.T) {
+	directory := t.TempDir()
+	for index, key := range []string{"aga", "agave", "aga"} {
+		var data bytes.Buffer
+		writer, err := zstd.NewWriter(&data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		line := "x - - [1" + string(rune('5'+index)) + "/Aug/2026:01:29:51 +0000] \"GET /search?key=" + key + " HTTP/1.1\" 200 49 \"-\" \"Dart\" 1ms\n"
+		if _, err := writer.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(directory, "access.log."+string(rune('1'+index))+".zst")
+		if err := os.WriteFile(name, data.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	searches, stats, err := collectLoggedSearches(directory, bucketDay, 3)
+	if err != nil || stats.Files != 3 || stats.Searches != 3 {
+		t.Fatalf("unexpected result: stats=%+v err=%v", stats, err)
+	}
+	aga := searches["aga"]
+	if aga.Count != 2 || aga.FirstSeen != "15/Aug/2026:01:29:51 +0000" || aga.LastSeen != "17/Aug/2026:01:29:51 +0000" || len(aga.Periods) != 2 {
+		t.Fatalf("expected merged aga rotations, got %#v", aga)
+	}
+}
+
+func TestScanMixedLogStreamSkipsAccessSearchesCoveredByEvents(t *testing.T) {
+	lines := []string{
+		`{"time":"2026-08-18T01:29:52Z","key":"aga","allowed":1,"notAllowed":0,"client":"android"}`,
+		`x - - [18/Aug/2026:01:29:50 +0000] "GET /search?key=aga HTTP/1.1" 200 49 "-" "Dart" 1ms`,
+		`x - - [18/Aug/2026:01:29:52 +0000] "GET /search?key=aga HTTP/1.1" 200 49 "-" "Dart" 1ms`,
+		`x - - [18/Aug/2026:01:29:54 +0000] "GET /robots.txt HTTP/1.1" 404 19 "-" "-" 0ms`,
+	}
+	scan, err := scanMixedLogStream(strings.NewReader(strings.Join(lines, "\n")+"\n"), bucketNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := logScanStats{Lines: 4, Searches: 2, Other: 1, Overlap: 1}
+	if scan.Stats != want {
+		t.Fatalf("expected stats %+v, got %+v", want, scan.Stats)
+	}
+	if scan.Searches["aga"].Count != 2 || scan.Searches["aga"].FirstSeen != "18/Aug/2026:01:29:50 +0000" {
+		t.Fatalf("expected access-log history plus event for aga, got %#v", scan.Searches["aga"])
+	}
+}
@@ -2,29 +2,49 @@ package main
 
 import (
 	"bufio"
-	"compress/gzip"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"net/url"
 	"os"
-	"path/filepath"
-	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
 )
 
-var accessSearchLogPattern = regexp.MustCompile(`\[([^]]+)\] "GET ([^ ]+) HTTP/[^ ]+" ([0-9]+) `)
+const accessLogTimeLayout = "02/Jan/2006:15:04:05 -0700"
 
 type loggedSearch struct {
-	Count     int
-	Statuses  map[int]int
-	FirstSeen string
-	LastSeen  string
+	Count       int
+	Statuses    map[int]int
+	FirstSeen   string
+	LastSeen    string
+	ZeroResults int
+	Clients     map[string]int
+	Periods     map[string]searchPeriod
+}
+
+// searchPeriod counts one key's requests within a day or week bucket. Events
+// is the subset read from search events, which carry result counts.
+type searchPeriod struct {
+	Count       int `json:"count"`
+	Events      int `json:"events"`
+	ZeroResults int `json:"zeroResults"`
+}
+
+// searchEvent mirrors the API's JSONL search analytics record.
+type searchEvent struct {
+	Time       time.Time `json:"time"`
+	Key        string    `json:"key"`
+	Type       string    `json:"type"`
+	Allowed    int       `json:"allowed"`
+	NotAllowed int       `json:"notAllowed"`
+	LatencyMS  float64   `json:"latencyMs"`
+	Client     string    `json:"client"`
 }
 
 func main() {
@@ -36,26 +56,49 @@ func main() {
 
 func runCLI(args []string) error {
 	if len(args) == 0 {
-		return errors.New("usage: search_coverage <extract|check> [options]")
+		return errors.New("usage: search_coverage <extract|check|suggest|trends|diff|backlog|duplicates|tune|golden> [options]")
 	}
 	switch args[0] {
 	case "extract":
 		return extractSearches(args[1:])
 	case "check":
 		return checkSearches(args[1:])
+	case "suggest":
+		return suggestAliases(args[1:])
+	case "trends":
+		return reportTrends(args[1:])
+	case "diff":
+		return diffCatalogs(args[1:])
+	case "backlog":
+		return buildBacklog(args[1:])
+	case "duplicates":
+		return findDuplicates(args[1:])
+	case "tune":
+		return tuneMatching(args[1:])
+	case "golden":
+		return checkGoldenQueries(args[1:])
 	default:
-		return fmt.Errorf("unknown mode %q; use extract or check", args[0])
+		return fmt.Errorf("unknown mode %q; use extract, check, suggest, trends, diff, backlog, duplicates, tune or golden", args[0])
 	}
 }
 
 func extractSearches(args []string) error {
 	flags := flag.NewFlagSet("extract", flag.ContinueOnError)
-	logs := flags.String("logs", "/srv/logs/aip-food-lookup/api", "directory containing access.log files")
-	output := flags.String("output", "searches.tsv", "output TSV path")
+	logs := flags.String("logs", "/srv/logs/aip-food-lookup/api", "directory containing access.log and search.log files, or - for stdin")
+	output := flags.String("output", "searches.tsv", "output path")
+	format := flags.String("format", formatTSV, "output format: tsv, json or csv")
+	bucket := flags.String("bucket", bucketDay, "trend bucket: day, week or none")
+	workers := flags.Int("workers", runtime.NumCPU(), "log files to read in parallel")
 	if err := flags.Parse(args); err != nil {
 		return err
 	}
-	searches, total, err := collectLoggedSearches(*logs)
+	if err := checkFormat(*format, formatTSV, formatJSON, formatCSV); err != nil {
+		return err
+	}
+	if *bucket != bucketDay && *bucket != bucketWeek && *bucket != bucketNone {
+		return fmt.Errorf("unknown bucket %q; use day, week or none", *bucket)
+	}
+	searches, stats, err := collectLoggedSearches(*logs, *bucket, *workers)
 	if err != nil {
 		return err
 	}
@@ -65,26 +108,34 @@ func extractSearches(args []string) error {
 	}
 	defer file.Close()
 	writer := bufio.NewWriter(file)
-	fmt.Fprintln(writer, "# AIP Food Lookup search coverage export")
-	fmt.Fprintln(writer, "# key\tcount\tfirst_seen\tlast_seen\tstatuses")
-	for _, key := range sortedKeys(searches) {
-		search := searches[key]
-		fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%s\n", key, search.Count, search.FirstSeen, search.LastSeen, formatStatuses(search.Statuses))
+	if err := writeSearchExport(writer, *format, searches); err != nil {
+		return err
 	}
 	if err := writer.Flush(); err != nil {
 		return fmt.Errorf("write output %s: %w", *output, err)
 	}
-	fmt.Printf("Wrote %d unique search keys (%d requests) to %s\n", len(searches), total, *output)
+	fmt.Println(stats)
+	fmt.Printf("Wrote %d unique search keys (%d requests) to %s\n", len(searches), stats.Searches, *output)
 	return nil
 }
 
 func checkSearches(args []string) error {
 	flags := flag.NewFlagSet("check", flag.ContinueOnError)
-	input := flags.String("input", "searches.tsv", "TSV exported by extract mode")
+	input := flags.String("input", "searches.tsv", "TSV, JSON or CSV exported by extract mode")
 	catalog := flags.String("catalog", "../../data", "local repository data directory")
+	format := flags.String("format", formatText, "output format: text, json, csv or markdown")
+	output := flags.String("output", "-", "output path, or - for stdout")
+	minCoverage := flags.Float64("min-coverage", 0, "fail when weighted coverage percent is below this value")
+	options := matchOptionFlags(flags)
 	if err := flags.Parse(args); err != nil {
 		return err
 	}
+	if err := checkFormat(*format, formatText, formatJSON, formatCSV, formatMarkdown); err != nil {
+		return err
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
 	searches, err := readSearchExport(*input)
 	if err != nil {
 		return err
@@ -93,136 +144,135 @@ func checkSearches(args []string) error {
 	if err != nil {
 		return fmt.Errorf("load local catalog: %w", err)
 	}
-	covered := 0
-	for _, key := range sortedKeys(searches) {
-		if foodcatalog.Covered(foods, key) {
-			covered++
-		}
-	}
-	fmt.Printf("Local catalog: %s\nSearch keys:   %d\nCovered:       %d\nUncovered:     %d\n\n", *catalog, len(searches), covered, len(searches)-covered)
-	fmt.Println("UNCOVERED SEARCHES")
-	fmt.Println("count | key | statuses | first seen | last seen")
-	fmt.Println("------|-----|----------|------------|----------")
-	for _, key := range sortedKeys(searches) {
-		if !foodcatalog.Covered(foods, key) {
-			search := searches[key]
-			fmt.Printf("%5d | %s | %s | %s | %s\n", search.Count, key, formatStatuses(search.Statuses), search.FirstSeen, search.LastSeen)
-		}
+	report := buildCoverageReport(*catalog, searches, foods, *options)
+	if err := writeOutput(*output, func(writer io.Writer) error {
+		return writeCoverageReport(writer, *format, report)
+	}); err != nil {
+		return err
 	}
-	fmt.Println()
-	fmt.Println("COVERED SEARCHES")
-	fmt.Println("count | key")
-	fmt.Println("------|-----")
-	for _, key := range sortedKeys(searches) {
-		if foodcatalog.Covered(foods, key) {
-			fmt.Printf("%5d | %s\n", searches[key].Count, key)
-		}
+	if coverage := weightedCoverage(report); coverage < *minCoverage {
+		return fmt.Errorf("weighted search coverage %.1f%% is below --min-coverage %.1f%%", coverage, *minCoverage)
 	}
 	return nil
 }
 
-func collectLoggedSearches(directory string) (map[string]loggedSearch, int, error) {
-	paths, err := filepath.Glob(filepath.Join(directory, "access.log*"))
-	if err != nil {
-		return nil, 0, err
-	}
-	sort.Strings(paths)
-	searches := make(map[string]loggedSearch)
-	total := 0
-	for _, path := range paths {
-		if err := scanAccessLog(path, searches, &total); err != nil {
-			return nil, total, err
+// weightedCoverage is the percentage of logged requests, not unique keys,
+// that the catalog covers.
+func weightedCoverage(report coverageReport) float64 {
+	total, covered := 0, 0
+	for _, row := range report.Searches {
+		total += row.Count
+		if row.Covered {
+			covered += row.Count
 		}
 	}
-	return searches, total, nil
+	if total == 0 {
+		return 100
+	}
+	return float64(covered) * 100 / float64(total)
 }
 
-func scanAccessLog(path string, searches map[string]loggedSearch, total *int) error {
-	file, err := os.Open(path)
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("open access log %s: %w", path, err)
+		return fmt.Errorf("create output %s: %w", path, err)
 	}
 	defer file.Close()
-	var reader io.Reader = file
-	if strings.HasSuffix(path, ".gz") {
-		compressed, err := gzip.NewReader(file)
-		if err != nil {
-			return fmt.Errorf("open gzip access log %s: %w", path, err)
-		}
-		defer compressed.Close()
-		reader = compressed
+	writer := bufio.NewWriter(file)
+	if err := write(writer); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("write output %s: %w", path, err)
+	}
+	return nil
+}
+
+func recordSearch(searches map[string]loggedSearch, key string, seen time.Time, status int, bucket string) loggedSearch {
+	search := searches[key]
+	if search.Statuses == nil {
+		search.Statuses = make(map[int]int)
+	}
+	if search.Clients == nil {
+		search.Clients = make(map[string]int)
+	}
+	if search.Periods == nil {
+		search.Periods = make(map[string]searchPeriod)
+	}
+	search.Count++
+	search.Statuses[status]++
+	if first, err := time.Parse(accessLogTimeLayout, search.FirstSeen); err != nil || seen.Before(first) {
+		search.FirstSeen = seen.Format(accessLogTimeLayout)
 	}
+	if last, err := time.Parse(accessLogTimeLayout, search.LastSeen); err != nil || seen.After(last) {
+		search.LastSeen = seen.Format(accessLogTimeLayout)
+	}
+	if period, ok := periodKey(seen, bucket); ok {
+		counts := search.Periods[period]
+		counts.Count++
+		search.Periods[period] = counts
+	}
+	return search
+}
+
+func readSearchExportTSV(reader io.Reader) (map[string]loggedSearch, error) {
+	searches := make(map[string]loggedSearch)
 	scanner := bufio.NewScanner(reader)
-	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 	for scanner.Scan() {
-		match := accessSearchLogPattern.FindStringSubmatch(scanner.Text())
-		if len(match) == 0 {
-			continue
-		}
-		target, err := url.ParseRequestURI(match[2])
-		if err != nil || target.Path != "/search" {
-			continue
-		}
-		key := strings.TrimSpace(target.Query().Get("key"))
-		status, err := strconv.Atoi(match[3])
-		if key == "" || err != nil {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
 			continue
 		}
-		search := searches[key]
-		if search.Statuses == nil {
-			search.Statuses = make(map[int]int)
-		}
-		search.Count++
-		search.Statuses[status]++
-		if search.FirstSeen == "" || match[1] < search.FirstSeen {
-			search.FirstSeen = match[1]
-		}
-		if match[1] > search.LastSeen {
-			search.LastSeen = match[1]
+		if err := parseExportFields(strings.Split(line, "\t"), searches); err != nil {
+			return nil, err
 		}
-		searches[key] = search
-		*total++
 	}
-	return scanner.Err()
+	return searches, scanner.Err()
 }
 
-func readSearchExport(path string) (map[string]loggedSearch, error) {
-	file, err := os.Open(path)
+// parseExportFields reads one export row; five- and seven-column rows predate
+// the zero_results/clients and periods columns.
+func parseExportFields(fields []string, searches map[string]loggedSearch) error {
+	line := strings.Join(fields, "\t")
+	if len(fields) != 5 && len(fields) != 7 && len(fields) != 8 {
+		return fmt.Errorf("invalid search export line: %q", line)
+	}
+	count, err := strconv.Atoi(fields[1])
 	if err != nil {
-		return nil, fmt.Errorf("open search export %s: %w", path, err)
+		return fmt.Errorf("invalid count in search export: %q", line)
 	}
-	defer file.Close()
-	searches := make(map[string]loggedSearch)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
-			continue
+	statuses := make(map[int]int)
+	for _, entry := range strings.Split(fields[4], ",") {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid statuses in search export: %q", line)
 		}
-		fields := strings.Split(line, "\t")
-		if len(fields) != 5 {
-			return nil, fmt.Errorf("invalid search export line: %q", line)
+		status, statusErr := strconv.Atoi(parts[0])
+		statusCount, countErr := strconv.Atoi(parts[1])
+		if statusErr != nil || countErr != nil {
+			return fmt.Errorf("invalid statuses in search export: %q", line)
 		}
-		count, err := strconv.Atoi(fields[1])
-		if err != nil {
-			return nil, fmt.Errorf("invalid count in search export: %q", line)
+		statuses[status] = statusCount
+	}
+	search := loggedSearch{Count: count, FirstSeen: fields[2], LastSeen: fields[3], Statuses: statuses, Clients: make(map[string]int), Periods: make(map[string]searchPeriod)}
+	if len(fields) >= 7 {
+		if search.ZeroResults, err = strconv.Atoi(fields[5]); err != nil {
+			return fmt.Errorf("invalid zero results in search export: %q", line)
 		}
-		statuses := make(map[int]int)
-		for _, entry := range strings.Split(fields[4], ",") {
-			parts := strings.SplitN(entry, ":", 2)
-			if len(parts) != 2 {
-				return nil, fmt.Errorf("invalid statuses in search export: %q", line)
-			}
-			status, statusErr := strconv.Atoi(parts[0])
-			statusCount, countErr := strconv.Atoi(parts[1])
-			if statusErr != nil || countErr != nil {
-				return nil, fmt.Errorf("invalid statuses in search export: %q", line)
-			}
-			statuses[status] = statusCount
+		if search.Clients, err = parseClients(fields[6]); err != nil {
+			return fmt.Errorf("invalid clients in search export: %q", line)
 		}
-		searches[fields[0]] = loggedSearch{Count: count, FirstSeen: fields[2], LastSeen: fields[3], Statuses: statuses}
 	}
-	return searches, scanner.Err()
+	if len(fields) == 8 {
+		if search.Periods, err = parsePeriods(fields[7]); err != nil {
+			return fmt.Errorf("invalid periods in search export: %q", line)
+		}
+	}
+	searches[fields[0]] = search
+	return nil
 }
 
 func sortedKeys(searches map[string]loggedSearch) []string {
@@ -246,3 +296,38 @@ func formatStatuses(statuses map[int]int) string {
 	}
 	return strings.Join(parts, ",")
 }
+
+func formatClients(clients map[string]int) string {
+	if len(clients) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(clients))
+	for key := range clients {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s:%d", key, clients[key]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseClients(value string) (map[string]int, error) {
+	clients := make(map[string]int)
+	if value == "-" || value == "" {
+		return clients, nil
+	}
+	for _, entry := range strings.Split(value, ",") {
+		index := strings.LastIndex(entry, ":")
+		if index <= 0 {
+			return nil, fmt.Errorf("invalid client count %q", entry)
+		}
+		count, err := strconv.Atoi(entry[index+1:])
+		if err != nil {
+			return nil, err
+		}
+		clients[entry[:index]] = count
+	}
+	return clients, nil
+}
@@ -29,9 +29,52 @@ func TestCollectLoggedSearchesReadsPlainAndGzipLogs(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	searches, total, err := collectLoggedSearches(directory)
-	if err != nil || total != 2 || searches["aga"].Count != 1 || searches["agave"].Count != 1 {
-		t.Fatalf("unexpected result: total=%d searches=%v err=%v", total, searches, err)
+	searches, stats, err := collectLoggedSearches(directory, bucketDay, 2)
+	if err != nil || stats.Searches != 2 || searches["aga"].Count != 1 || searches["agave"].Count != 1 {
+		t.Fatalf("unexpected result: stats=%+v searches=%v err=%v", stats, searches, err)
+	}
+}
+
+func TestCollectLoggedSearchesReadsSearchEventsAfterAccessLogs(t *testing.T) {
+	directory := t.TempDir()
+	access := "x - - [18/Aug/2026:01:29:50 +0000] \"GET /search?key=aga HTTP/1.1\" 200 49 \"-\" \"Dart\" 1ms\n"
+	access += "x - - [18/Aug/2026:01:29:52 +0000] \"GET /search?key=aga HTTP/1.1\" 200 49 \"-\" \"Dart\" 1ms\n"
+	events := `{"time":"2026-08-18T01:29:52Z","key":"aga","type":"searchbytext","allowed":1,"notAllowed":0,"latencyMs":0.2,"client":"android"}` + "\n"
+	events += `{"time":"2026-08-18T01:29:53Z","key":"zzzz","type":"searchbytext","allowed":0,"notAllowed":0,"latencyMs":0.1,"client":"ios"}` + "\n"
+	if err := os.WriteFile(filepath.Join(directory, "access.log"), []byte(access), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, "search.log"), []byte(events), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	searches, stats, err := collectLoggedSearches(directory, bucketDay, 2)
+	if err != nil || stats.Searches != 3 || stats.Overlap != 1 {
+		t.Fatalf("unexpected result: stats=%+v searches=%v err=%v", stats, searches, err)
+	}
+	if searches["aga"].Count != 2 || searches["aga"].FirstSeen != "18/Aug/2026:01:29:50 +0000" || searches["aga"].Clients["android"] != 1 {
+		t.Fatalf("expected access-log history plus event for aga, got %#v", searches["aga"])
+	}
+	if searches["zzzz"].ZeroResults != 1 {
+		t.Fatalf("expected zero-result event for zzzz, got %#v", searches["zzzz"])
+	}
+}
+
+func TestReadSearchExportAcceptsLegacyAndEventColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "searches.tsv")
+	export := "# key\tcount\tfirst_seen\tlast_seen\tstatuses\n"
+	export += "aga\t2\ta\tb\t200:2\n"
+	export += "zzzz\t3\ta\tb\t200:3\t3\tandroid:2,ios:1\n"
+	if err := os.WriteFile(path, []byte(export), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	searches, err := readSearchExport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if searches["aga"].Count != 2 || searches["zzzz"].ZeroResults != 3 || searches["zzzz"].Clients["ios"] != 1 {
+		t.Fatalf("unexpected export: %#v", searches)
 	}
 }
 
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+// matchOptionFlags registers the matcher threshold flags, defaulting to the
+// thresholds the catalog was tuned with, so a mode can replay searches the
+// way an API with tuned AIP__API__Matching settings would. Check the
+// returned options with Validate after parsing.
+func matchOptionFlags(flags *flag.FlagSet) *foodcatalog.MatchOptions {
+	options := foodcatalog.DefaultMatchOptions()
+	flags.IntVar(&options.ShortQueryLength, "short-query-length", options.ShortQueryLength, "longest query, in characters, that uses --short-distance")
+	flags.IntVar(&options.ShortDistance, "short-distance", options.ShortDistance, "edit distance allowed for short queries")
+	flags.IntVar(&options.LongDistance, "long-distance", options.LongDistance, "edit distance allowed for longer queries")
+	flags.IntVar(&options.SoundBonus, "sound-bonus", options.SoundBonus, "extra distance allowed when a longer query sounds alike")
+	flags.IntVar(&options.MinTokenLength, "min-token-length", options.MinTokenLength, "shortest word compared on its own")
+	return &options
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+	"gopkg.in/yaml.v3"
+)
+
+// aliasProposal is one reviewable entry of the suggest mode YAML patch.
+type aliasProposal struct {
+	Search       string           `yaml:"search"`
+	Count        int              `yaml:"count"`
+	File         string           `yaml:"file"`
+	Name         string           `yaml:"name"`
+	AddAliases   []string         `yaml:"add_aliases"`
+	Distance     int              `yaml:"distance"`
+	Sound        bool             `yaml:"sound"`
+	Alternatives []aliasCandidate `yaml:"alternatives,omitempty"`
+}
+
+type aliasCandidate struct {
+	File     string `yaml:"file"`
+	Name     string `yaml:"name"`
+	Distance int    `yaml:"distance"`
+	Sound    bool   `yaml:"sound"`
+}
+
+func suggestAliases(args []string) error {
+	flags := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	input := flags.String("input", "searches.tsv", "TSV exported by extract mode")
+	catalog := flags.String("catalog", "../../data", "local repository data directory")
+	output := flags.String("output", "alias-suggestions.yaml", "output YAML patch path")
+	candidates := flags.Int("candidates", 3, "nearest catalog foods to consider per search")
+	options := matchOptionFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	searches, err := readSearchExport(*input)
+	if err != nil {
+		return err
+	}
+	foods, err := foodcatalog.Load(*catalog)
+	if err != nil {
+		return fmt.Errorf("load local catalog: %w", err)
+	}
+	proposals, unmatched := buildAliasProposals(searches, foods, *catalog, *candidates, *options)
+	data, err := yaml.Marshal(proposals)
+	if err != nil {
+		return err
+	}
+	header := "# AIP Food Lookup alias suggestions for uncovered searches, ranked by search count.\n" +
+		"# Review each entry and copy accepted add_aliases into the named catalog file.\n"
+	if err := os.WriteFile(*output, append([]byte(header), data...), 0644); err != nil {
+		return fmt.Errorf("write output %s: %w", *output, err)
+	}
+	fmt.Printf("Wrote %d alias suggestions to %s (%d uncovered searches had no nearby food)\n", len(proposals), *output, unmatched)
+	return nil
+}
+
+// buildAliasProposals proposes the nearest catalog food for every uncovered
+// search, most searched first.
+func buildAliasProposals(searches map[string]loggedSearch, foods []foodcatalog.Food, catalog string, candidates int, options foodcatalog.MatchOptions) ([]aliasProposal, int) {
+	proposals := []aliasProposal{}
+	unmatched := 0
+	for _, key := range sortedKeys(searches) {
+		if options.Covered(foods, key) {
+			continue
+		}
+		neighbors := options.NearestFoods(foods, key, candidates)
+		if len(neighbors) == 0 {
+			unmatched++
+			continue
+		}
+		best := neighbors[0]
+		proposal := aliasProposal{
+			Search:     key,
+			Count:      searches[key].Count,
+			File:       catalogRelativePath(catalog, best.Food.Source),
+			Name:       best.Food.Name,
+			AddAliases: []string{key},
+			Distance:   best.Distance,
+			Sound:      best.Sound,
+		}
+		for _, neighbor := range neighbors[1:] {
+			proposal.Alternatives = append(proposal.Alternatives, aliasCandidate{
+				File:     catalogRelativePath(catalog, neighbor.Food.Source),
+				Name:     neighbor.Food.Name,
+				Distance: neighbor.Distance,
+				Sound:    neighbor.Sound,
+			})
+		}
+		proposals = append(proposals, proposal)
+	}
+	sort.SliceStable(proposals, func(i, j int) bool {
+		return proposals[i].Count > proposals[j].Count
+	})
+	return proposals, unmatched
+}
+
+func catalogRelativePath(catalog string, source string) string {
+	relative, err := filepath.Rel(catalog, source)
+	if err != nil {
+		return filepath.ToSlash(source)
+	}
+	return filepath.ToSlash(relative)
+}
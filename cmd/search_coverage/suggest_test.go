@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuildAliasProposalsRanksUncoveredSearchesByCount(t *testing.T) {
+	directory := t.TempDir()
+	if err := os.Mkdir(filepath.Join(directory, "allowed"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, "allowed", "fruits.yaml"), []byte("- name: Apples\n- name: Bananas\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	foods, err := foodcatalog.Load(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	searches := map[string]loggedSearch{
+		"apples": {Count: 50},
+		"nanas":  {Count: 2},
+		"pples":  {Count: 9},
+		"qqqqqq": {Count: 30},
+	}
+
+	proposals, unmatched := buildAliasProposals(searches, foods, directory, 3, foodcatalog.DefaultMatchOptions())
+	if unmatched != 1 || len(proposals) != 2 {
+		t.Fatalf("unexpected proposals: unmatched=%d proposals=%#v", unmatched, proposals)
+	}
+	if proposals[0].Search != "pples" || proposals[0].Name != "Apples" || proposals[0].File != "allowed/fruits.yaml" {
+		t.Fatalf("expected most searched proposal first, got %#v", proposals[0])
+	}
+
+	data, err := yaml.Marshal(proposals)
+	if err != nil || !strings.Contains(string(data), "add_aliases:\n    - pples\n") {
+		t.Fatalf("unexpected YAML patch: %s err=%v", string(data), err)
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	bucketDay  = "day"
+	bucketWeek = "week"
+	bucketNone = "none"
+
+	dayPeriodLayout = "2006-01-02"
+)
+
+// trendPeriod summarizes one day or week of logged searches.
+type trendPeriod struct {
+	Period         string        `json:"period"`
+	Requests       int           `json:"requests"`
+	UniqueKeys     int           `json:"uniqueKeys"`
+	NewKeys        []string      `json:"newKeys"`
+	Events         int           `json:"events"`
+	ZeroResults    int           `json:"zeroResults"`
+	ZeroResultRate *float64      `json:"zeroResultRate"`
+	TopSearches    []trendSearch `json:"topSearches"`
+}
+
+type trendSearch struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+func reportTrends(args []string) error {
+	flags := flag.NewFlagSet("trends", flag.ContinueOnError)
+	input := flags.String("input", "searches.tsv", "TSV, JSON or CSV exported by extract mode")
+	period := flags.String("period", "", "roll daily buckets up to week; defaults to the export bucket")
+	since := flags.String("since", "", "first period to include, e.g. 18/Aug/2026:00:00:00 +0000 or 2026-08-18")
+	until := flags.String("until", "", "last period to include, in the same formats as --since")
+	top := flags.Int("top", 10, "top searches to list per period")
+	format := flags.String("format", formatText, "output format: text or json")
+	output := flags.String("output", "-", "output path, or - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatText, formatJSON); err != nil {
+		return err
+	}
+	searches, err := readSearchExport(*input)
+	if err != nil {
+		return err
+	}
+	if *period != "" && *period != bucketDay && *period != bucketWeek {
+		return fmt.Errorf("unknown period %q; use day or week", *period)
+	}
+	var sinceTime, untilTime time.Time
+	if *since != "" {
+		if sinceTime, err = parseTrendTime(*since); err != nil {
+			return err
+		}
+	}
+	if *until != "" {
+		if untilTime, err = parseTrendTime(*until); err != nil {
+			return err
+		}
+	}
+	periods, err := buildTrends(searches, *period, sinceTime, untilTime, *top)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, func(writer io.Writer) error {
+		return writeTrends(writer, *format, periods)
+	})
+}
+
+// buildTrends groups export periods, optionally rolling days into ISO weeks.
+// A key counts as new in the first period it was ever seen, even when that
+// period falls outside the --since/--until window.
+func buildTrends(searches map[string]loggedSearch, rollup string, since time.Time, until time.Time, top int) ([]trendPeriod, error) {
+	byPeriod := make(map[string]*trendPeriod)
+	keyCounts := make(map[string]map[string]int)
+	for _, key := range sortedKeys(searches) {
+		firstPeriod := ""
+		for period, counts := range searches[key].Periods {
+			if rollup == bucketWeek && !strings.Contains(period, "-W") {
+				start, err := periodStart(period)
+				if err != nil {
+					return nil, err
+				}
+				period = weekPeriod(start)
+			}
+			if firstPeriod == "" || period < firstPeriod {
+				firstPeriod = period
+			}
+			start, err := periodStart(period)
+			if err != nil {
+				return nil, err
+			}
+			if (!since.IsZero() && start.Before(truncateToPeriod(since, period))) || (!until.IsZero() && start.After(until)) {
+				continue
+			}
+			trend := byPeriod[period]
+			if trend == nil {
+				trend = &trendPeriod{Period: period, NewKeys: []string{}}
+				byPeriod[period] = trend
+				keyCounts[period] = make(map[string]int)
+			}
+			trend.Requests += counts.Count
+			trend.Events += counts.Events
+			trend.ZeroResults += counts.ZeroResults
+			keyCounts[period][key] += counts.Count
+		}
+		if trend := byPeriod[firstPeriod]; trend != nil {
+			trend.NewKeys = append(trend.NewKeys, key)
+		}
+	}
+
+	periods := make([]trendPeriod, 0, len(byPeriod))
+	for period, trend := range byPeriod {
+		trend.UniqueKeys = len(keyCounts[period])
+		if trend.Events > 0 {
+			rate := float64(trend.ZeroResults) / float64(trend.Events)
+			trend.ZeroResultRate = &rate
+		}
+		trend.TopSearches = topSearches(keyCounts[period], top)
+		periods = append(periods, *trend)
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Period < periods[j].Period
+	})
+	return periods, nil
+}
+
+func topSearches(counts map[string]int, limit int) []trendSearch {
+	result := make([]trendSearch, 0, len(counts))
+	for key, count := range counts {
+		result = append(result, trendSearch{Key: key, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Key < result[j].Key
+	})
+	if limit >= 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func writeTrends(writer io.Writer, format string, periods []trendPeriod) error {
+	switch format {
+	case formatText:
+		fmt.Fprintln(writer, "period | requests | unique | new | zero-result rate | top searches")
+		fmt.Fprintln(writer, "-------|----------|--------|-----|------------------|-------------")
+		for _, period := range periods {
+			top := make([]string, 0, len(period.TopSearches))
+			for _, search := range period.TopSearches {
+				top = append(top, fmt.Sprintf("%s (%d)", search.Key, search.Count))
+			}
+			fmt.Fprintf(writer, "%s | %d | %d | %d | %s | %s\n", period.Period, period.Requests, period.UniqueKeys, len(period.NewKeys), formatRate(period.ZeroResultRate), strings.Join(top, ", "))
+		}
+		return nil
+	case formatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(periods)
+	default:
+		return fmt.Errorf("unknown format %q; use text or json", format)
+	}
+}
+
+func formatRate(rate *float64) string {
+	if rate == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*rate*100, 'f', 1, 64) + "%"
+}
+
+// periodKey names the bucket a search falls in, using UTC days and ISO weeks.
+func periodKey(seen time.Time, bucket string) (string, bool) {
+	switch bucket {
+	case bucketDay:
+		return seen.UTC().Format(dayPeriodLayout), true
+	case bucketWeek:
+		return weekPeriod(seen), true
+	default:
+		return "", false
+	}
+}
+
+func weekPeriod(seen time.Time) string {
+	year, week := seen.UTC().ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// periodStart returns the UTC start of a day or ISO week period key.
+func periodStart(period string) (time.Time, error) {
+	if !strings.Contains(period, "-W") {
+		return time.Parse(dayPeriodLayout, period)
+	}
+	var year, week int
+	if _, err := fmt.Sscanf(period, "%04d-W%02d", &year, &week); err != nil {
+		return time.Time{}, fmt.Errorf("invalid week period %q", period)
+	}
+	januaryFourth := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	offset := (int(januaryFourth.Weekday()) + 6) % 7
+	return januaryFourth.AddDate(0, 0, -offset+(week-1)*7), nil
+}
+
+// truncateToPeriod moves since back to the start of its period so a window
+// starting mid-day or mid-week still includes that bucket.
+func truncateToPeriod(since time.Time, period string) time.Time {
+	if strings.Contains(period, "-W") {
+		start, err := periodStart(weekPeriod(since))
+		if err == nil {
+			return start
+		}
+	}
+	day := since.UTC()
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseTrendTime accepts the access-log timestamp format plus plain dates and
+// RFC 3339 for convenience.
+func parseTrendTime(value string) (time.Time, error) {
+	for _, layout := range []string{accessLogTimeLayout, dayPeriodLayout, time.RFC3339} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q; use %s or %s", value, accessLogTimeLayout, dayPeriodLayout)
+}
+
+func formatPeriods(periods map[string]searchPeriod) string {
+	if len(periods) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(periods))
+	for key := range periods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		counts := periods[key]
+		parts = append(parts, fmt.Sprintf("%s=%d/%d/%d", key, counts.Count, counts.Events, counts.ZeroResults))
+	}
+	return strings.Join(parts, ",")
+}
+
+// parsePeriods reads the period=count/events/zero_results export column.
+func parsePeriods(value string) (map[string]searchPeriod, error) {
+	periods := make(map[string]searchPeriod)
+	if value == "-" || value == "" {
+		return periods, nil
+	}
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid period %q", entry)
+		}
+		var counts searchPeriod
+		if _, err := fmt.Sscanf(parts[1], "%d/%d/%d", &counts.Count, &counts.Events, &counts.ZeroResults); err != nil {
+			return nil, fmt.Errorf("invalid period %q", entry)
+		}
+		periods[parts[0]] = counts
+	}
+	return periods, nil
+}
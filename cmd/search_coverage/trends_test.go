@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCollectLoggedSearchesBucketsByDay(t *testing.T) {
+	directory := t.TempDir()
+	access := "x - - [31/Aug/2026:23:59:00 +0000] \"GET /search?key=aga HTTP/1.1\" 200 49 \"-\" \"Dart\" 1ms\n"
+	access += "x - - [01/Sep/2026:00:01:00 +0000] \"GET /search?key=aga HTTP/1.1\" 200 49 \"-\" \"Dart\" 1ms\n"
+	if err := os.WriteFile(filepath.Join(directory, "access.log"), []byte(access), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	searches, _, err := collectLoggedSearches(directory, bucketDay, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	search := searches["aga"]
+	if search.Periods["2026-08-31"].Count != 1 || search.Periods["2026-09-01"].Count != 1 {
+		t.Fatalf("unexpected periods: %#v", search.Periods)
+	}
+	if search.FirstSeen != "31/Aug/2026:23:59:00 +0000" || search.LastSeen != "01/Sep/2026:00:01:00 +0000" {
+		t.Fatalf("expected chronological first/last seen, got %q %q", search.FirstSeen, search.LastSeen)
+	}
+}
+
+func TestBuildTrendsReportsNewKeysAndZeroResultRate(t *testing.T) {
+	searches := map[string]loggedSearch{
+		"aga": {Periods: map[string]searchPeriod{
+			"2026-08-17": {Count: 3},
+			"2026-08-24": {Count: 5, Events: 5},
+		}},
+		"zzzz": {Periods: map[string]searchPeriod{
+			"2026-08-25": {Count: 2, Events: 2, ZeroResults: 2},
+		}},
+	}
+
+	periods, err := buildTrends(searches, bucketWeek, time.Time{}, time.Time{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 2 || periods[0].Period != "2026-W34" || periods[1].Period != "2026-W35" {
+		t.Fatalf("unexpected periods: %#v", periods)
+	}
+	week := periods[1]
+	if week.Requests != 7 || week.UniqueKeys != 2 || len(week.NewKeys) != 1 || week.NewKeys[0] != "zzzz" {
+		t.Fatalf("unexpected week summary: %#v", week)
+	}
+	if week.ZeroResultRate == nil || *week.ZeroResultRate < 0.28 || *week.ZeroResultRate > 0.29 {
+		t.Fatalf("unexpected zero-result rate: %v", week.ZeroResultRate)
+	}
+	if len(week.TopSearches) != 1 || week.TopSearches[0].Key != "aga" {
+		t.Fatalf("unexpected top searches: %#v", week.TopSearches)
+	}
+
+	since, err := parseTrendTime("25/Aug/2026:12:00:00 +0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	daily, err := buildTrends(searches, "", since, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(daily) != 1 || daily[0].Period != "2026-08-25" || daily[0].ZeroResultRate == nil || *daily[0].ZeroResultRate != 1 {
+		t.Fatalf("unexpected filtered periods: %#v", daily)
+	}
+}
+
+func TestPeriodStartHandlesISOWeeks(t *testing.T) {
+	start, err := periodStart("2026-W01")
+	if err != nil || start.Format(dayPeriodLayout) != "2025-12-29" {
+		t.Fatalf("unexpected week start %v err=%v", start, err)
+	}
+	if weekPeriod(start) != "2026-W01" {
+		t.Fatalf("unexpected round trip %q", weekPeriod(start))
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+// tuningResult scores one MatchOptions setting against the labelled
+// searches. True and false positives and false negatives are counted per
+// food and weighted by how often each search was logged.
+type tuningResult struct {
+	Options        foodcatalog.MatchOptions `json:"options"`
+	Default        bool                     `json:"default"`
+	TruePositives  int                      `json:"truePositives"`
+	FalsePositives int                      `json:"falsePositives"`
+	FalseNegatives int                      `json:"falseNegatives"`
+	Precision      float64                  `json:"precision"`
+	Recall         float64                  `json:"recall"`
+	F1             float64                  `json:"f1"`
+}
+
+// labelledSearch is one expected-results entry with its logged weight.
+type labelledSearch struct {
+	Key      string
+	Expected []string
+	Weight   int
+}
+
+func tuneMatching(args []string) error {
+	defaults := foodcatalog.DefaultMatchOptions()
+	flags := flag.NewFlagSet("tune", flag.ContinueOnError)
+	input := flags.String("input", "searches.tsv", "TSV, JSON or CSV exported by extract mode, or empty to weight every labelled search once")
+	expected := flags.String("expected", "expected-results.tsv", "labelled searches: key, then the expected food names, tab separated")
+	catalog := flags.String("catalog", "../../data", "local repository data directory")
+	typeSearch := flags.String("type", "searchbytextandsound", "search type to replay")
+	shortQueryLengths := flags.String("short-query-length", strconv.Itoa(defaults.ShortQueryLength), "comma separated ShortQueryLength values to try")
+	shortDistances := flags.String("short-distance", "0,1,2", "comma separated ShortDistance values to try")
+	longDistances := flags.String("long-distance", "2,3,4", "comma separated LongDistance values to try")
+	soundBonuses := flags.String("sound-bonus", "0,1,2", "comma separated SoundBonus values to try")
+	minTokenLengths := flags.String("min-token-length", strconv.Itoa(defaults.MinTokenLength), "comma separated MinTokenLength values to try")
+	format := flags.String("format", formatText, "output format: text, json or csv")
+	output := flags.String("output", "-", "output path, or - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatText, formatJSON, formatCSV); err != nil {
+		return err
+	}
+
+	grid := make([][]int, 0, 5)
+	for _, values := range []string{*shortQueryLengths, *shortDistances, *longDistances, *soundBonuses, *minTokenLengths} {
+		parsed, err := parseIntList(values)
+		if err != nil {
+			return err
+		}
+		grid = append(grid, parsed)
+	}
+	labels, err := readExpectedResults(*expected)
+	if err != nil {
+		return err
+	}
+	var searches map[string]loggedSearch
+	if *input != "" {
+		if searches, err = readSearchExport(*input); err != nil {
+			return err
+		}
+	}
+	foods, err := foodcatalog.Load(*catalog)
+	if err != nil {
+		return fmt.Errorf("load local catalog: %w", err)
+	}
+
+	labelled := weightLabelledSearches(labels, searches)
+	if len(labelled) == 0 {
+		return fmt.Errorf("none of the %d labelled searches appear in %s", len(labels), *input)
+	}
+	var results []tuningResult
+	for _, options := range matchOptionsGrid(grid) {
+		results = append(results, scoreMatchOptions(options, foods, labelled, *typeSearch))
+	}
+	rankTuningResults(results)
+	return writeOutput(*output, func(writer io.Writer) error {
+		return writeTuningResults(writer, *format, len(labelled), results)
+	})
+}
+
+// readExpectedResults reads a TSV of labelled searches. Each line is a search
+// key followed by the food names it should return; a key alone should return
+// nothing. Blank lines and # comments are skipped.
+func readExpectedResults(path string) (map[string][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open expected results %s: %w", path, err)
+	}
+	defer file.Close()
+
+	labels := make(map[string][]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		key := strings.TrimSpace(fields[0])
+		expected := []string{}
+		for _, field := range fields[1:] {
+			if name := strings.TrimSpace(field); name != "" {
+				expected = append(expected, name)
+			}
+		}
+		labels[key] = expected
+	}
+	return labels, scanner.Err()
+}
+
+// weightLabelledSearches keeps labelled keys that were logged, weighted by
+// their search count, or every key with weight one without a log export.
+func weightLabelledSearches(labels map[string][]string, searches map[string]loggedSearch) []labelledSearch {
+	counts := make(map[string]int)
+	for key, search := range searches {
+		counts[foodcatalog.Fold(key)] += search.Count
+	}
+	var labelled []labelledSearch
+	for key, expected := range labels {
+		weight := 1
+		if searches != nil {
+			weight = counts[foodcatalog.Fold(key)]
+		}
+		if weight > 0 {
+			labelled = append(labelled, labelledSearch{Key: key, Expected: expected, Weight: weight})
+		}
+	}
+	sort.Slice(labelled, func(i, j int) bool { return labelled[i].Key < labelled[j].Key })
+	return labelled
+}
+
+// matchOptionsGrid returns every valid combination of the ShortQueryLength,
+// ShortDistance, LongDistance, SoundBonus and MinTokenLength values.
+func matchOptionsGrid(grid [][]int) []foodcatalog.MatchOptions {
+	var combinations []foodcatalog.MatchOptions
+	for _, shortQueryLength := range grid[0] {
+		for _, shortDistance := range grid[1] {
+			for _, longDistance := range grid[2] {
+				for _, soundBonus := range grid[3] {
+					for _, minTokenLength := range grid[4] {
+						options := foodcatalog.MatchOptions{
+							ShortQueryLength: shortQueryLength,
+							ShortDistance:    shortDistance,
+							LongDistance:     longDistance,
+							SoundBonus:       soundBonus,
+							MinTokenLength:   minTokenLength,
+						}
+						if options.Validate() == nil {
+							combinations = append(combinations, options)
+						}
+					}
+				}
+			}
+		}
+	}
+	return combinations
+}
+
+func scoreMatchOptions(options foodcatalog.MatchOptions, foods []foodcatalog.Food, labelled []labelledSearch, typeSearch string) tuningResult {
+	result := tuningResult{Options: options, Default: options == foodcatalog.DefaultMatchOptions()}
+	for _, search := range labelled {
+		matched := options.Match(foods, search.Key, typeSearch)
+		got := make(map[string]bool)
+		for _, name := range append(matched.Allowed, matched.NotAllowed...) {
+			got[name] = true
+		}
+		for _, name := range search.Expected {
+			if got[name] {
+				result.TruePositives += search.Weight
+				delete(got, name)
+			} else {
+				result.FalseNegatives += search.Weight
+			}
+		}
+		result.FalsePositives += len(got) * search.Weight
+	}
+	result.Precision = ratio(result.TruePositives, result.TruePositives+result.FalsePositives)
+	result.Recall = ratio(result.TruePositives, result.TruePositives+result.FalseNegatives)
+	if result.Precision+result.Recall > 0 {
+		result.F1 = 2 * result.Precision * result.Recall / (result.Precision + result.Recall)
+	}
+	return result
+}
+
+// ratio is part/whole, or 1 when there is nothing to measure.
+func ratio(part int, whole int) float64 {
+	if whole == 0 {
+		return 1
+	}
+	return float64(part) / float64(whole)
+}
+
+// rankTuningResults orders settings by F1, then precision, keeping the
+// default setting ahead of equally scored alternatives.
+func rankTuningResults(results []tuningResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].F1 != results[j].F1 {
+			return results[i].F1 > results[j].F1
+		}
+		if results[i].Precision != results[j].Precision {
+			return results[i].Precision > results[j].Precision
+		}
+		return results[i].Default && !results[j].Default
+	})
+}
+
+func writeTuningResults(writer io.Writer, format string, searches int, results []tuningResult) error {
+	switch format {
+	case formatText:
+		fmt.Fprintf(writer, "Labelled searches: %d\n\n", searches)
+		fmt.Fprintln(writer, "f1 | precision | recall | tp | fp | fn | short query | short distance | long distance | sound bonus | min token | default")
+		fmt.Fprintln(writer, "---|-----------|--------|----|----|----|-------------|----------------|---------------|-------------|-----------|--------")
+		for _, result := range results {
+			options := result.Options
+			fmt.Fprintf(writer, "%.3f | %.3f | %.3f | %d | %d | %d | %d | %d | %d | %d | %d | %s\n",
+				result.F1, result.Precision, result.Recall, result.TruePositives, result.FalsePositives, result.FalseNegatives,
+				options.ShortQueryLength, options.ShortDistance, options.LongDistance, options.SoundBonus, options.MinTokenLength, defaultMarker(result.Default))
+		}
+		return nil
+	case formatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case formatCSV:
+		csvWriter := csv.NewWriter(writer)
+		_ = csvWriter.Write([]string{"f1", "precision", "recall", "true_positives", "false_positives", "false_negatives", "short_query_length", "short_distance", "long_distance", "sound_bonus", "min_token_length", "default"})
+		for _, result := range results {
+			options := result.Options
+			_ = csvWriter.Write([]string{
+				strconv.FormatFloat(result.F1, 'f', 3, 64), strconv.FormatFloat(result.Precision, 'f', 3, 64), strconv.FormatFloat(result.Recall, 'f', 3, 64),
+				strconv.Itoa(result.TruePositives), strconv.Itoa(result.FalsePositives), strconv.Itoa(result.FalseNegatives),
+				strconv.Itoa(options.ShortQueryLength), strconv.Itoa(options.ShortDistance), strconv.Itoa(options.LongDistance),
+				strconv.Itoa(options.SoundBonus), strconv.Itoa(options.MinTokenLength), strconv.FormatBool(result.Default),
+			})
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	default:
+		return fmt.Errorf("unknown format %q; use text, json or csv", format)
+	}
+}
+
+func defaultMarker(isDefault bool) string {
+	if isDefault {
+		return "yes"
+	}
+	return ""
+}
+
+func parseIntList(values string) ([]int, error) {
+	var parsed []int
+	for _, value := range strings.Split(values, ",") {
+		number, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in %q", value, values)
+		}
+		parsed = append(parsed, number)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CalypsoSys/aip_food_lookup/internal/foodcatalog"
+)
+
+func TestScoreMatchOptionsWeightsPrecisionAndRecall(t *testing.T) {
+	foods := loadTestCatalog(t, "Pork\nPorchetta\nBeef\n")
+	labelled := []labelledSearch{
+		{Key: "porc", Expected: []string{"Pork", "Porchetta"}, Weight: 3},
+		{Key: "bef", Expected: []string{"Beef"}, Weight: 1},
+		{Key: "qqqq", Expected: []string{}, Weight: 5},
+	}
+
+	strict := foodcatalog.DefaultMatchOptions()
+	strict.ShortDistance = 0
+	result := scoreMatchOptions(strict, foods, labelled, "searchbytext")
+	if result.TruePositives != 3 || result.FalseNegatives != 4 || result.FalsePositives != 0 || result.Precision != 1 {
+		t.Fatalf("unexpected strict text score: %#v", result)
+	}
+
+	result = scoreMatchOptions(foodcatalog.DefaultMatchOptions(), foods, labelled, "searchbytextandsound")
+	if !result.Default || result.TruePositives != 7 || result.FalseNegatives != 0 || result.Recall != 1 {
+		t.Fatalf("unexpected default score: %#v", result)
+	}
+}
+
+func TestTuneMatchingRanksSettingsFromLabelledLoggedSearches(t *testing.T) {
+	tempDir := t.TempDir()
+	foods := loadTestCatalog(t, "Pork\nPorchetta\nBeef\n")
+	catalog := filepath.Dir(filepath.Dir(foods[0].Source))
+	expected := filepath.Join(tempDir, "expected.tsv")
+	if err := os.WriteFile(expected, []byte("# key\texpected foods\nbef\tBeef\npork\tPork\nunlogged\tBeef\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	searches := filepath.Join(tempDir, "searches.tsv")
+	if err := os.WriteFile(searches, []byte("bef\t4\t\t\t200:4\npork\t2\t\t\t200:2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(tempDir, "tune.txt")
+
+	err := runCLI([]string{"tune", "--input", searches, "--expected", expected, "--catalog", catalog, "--short-distance", "0,1", "--long-distance", "3", "--sound-bonus", "1", "--output", output})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, _ := os.ReadFile(output)
+	lines := strings.Split(string(report), "\n")
+	if lines[0] != "Labelled searches: 2" || !strings.HasPrefix(lines[4], "1.000 | 1.000 | 1.000 | 6 | 0 | 0 | 4 | 1 | 3 | 1 | 3 | yes") {
+		t.Fatalf("expected the default setting to rank first, got:\n%s", report)
+	}
+
+	var buffer bytes.Buffer
+	if err := writeTuningResults(&buffer, formatCSV, 2, []tuningResult{{Options: foodcatalog.DefaultMatchOptions(), Default: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "0.000,0.000,0.000,0,0,0,4,1,3,1,3,true") {
+		t.Fatalf("unexpected csv: %s", buffer.String())
+	}
+}
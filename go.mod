@@ -2,6 +2,9 @@ module github.com/CalypsoSys/aip_food_lookup
 
 go 1.21
 
-require github.com/CalypsoSys/godoublemetaphone v0.1.1
-
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/CalypsoSys/godoublemetaphone v0.1.1
+	github.com/klauspost/compress v1.17.11
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
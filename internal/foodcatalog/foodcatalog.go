@@ -2,26 +2,34 @@ package foodcatalog
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/CalypsoSys/godoublemetaphone/pkg/godoublemetaphone"
 	"gopkg.in/yaml.v3"
 )
 
+// CatalogEntry is one food in a catalog file. Names holds optional
+// translations keyed by language, such as es or fr.
 type CatalogEntry struct {
-	Name    string   `yaml:"name"`
-	Aliases []string `yaml:"aliases,omitempty"`
+	Name    string                   `yaml:"name"`
+	Aliases []string                 `yaml:"aliases,omitempty"`
+	Names   map[string]LocalizedName `yaml:"names,omitempty"`
 }
 
 type Food struct {
 	Allowed                 bool
 	Name                    string
 	Aliases                 []string
+	Names                   map[string]LocalizedName
 	PrimaryShortMetaphone   uint16
 	AlternateShortMetaphone uint16
+	Source                  string
 }
 
 // ParseEntry reads a catalog line. The first tab-separated field is the
@@ -52,6 +60,9 @@ func LoadEntries(path string) ([]CatalogEntry, error) {
 		if err := yaml.Unmarshal(data, &entries); err != nil {
 			return nil, err
 		}
+		for index := range entries {
+			entries[index].Names = normalizeNames(entries[index].Names)
+		}
 		return entries, nil
 	}
 
@@ -71,6 +82,103 @@ func LoadEntries(path string) ([]CatalogEntry, error) {
 	return entries, scanner.Err()
 }
 
+// AppendEntry adds one entry to the end of a YAML or legacy .dat catalog file,
+// keeping the existing entries in their current order.
+func AppendEntry(path string, entry CatalogEntry) error {
+	if filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml" {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = file.WriteString(strings.Join(append([]string{entry.Name}, entry.Aliases...), "\t") + "\n")
+		return err
+	}
+
+	entries, err := LoadEntries(path)
+	if err != nil {
+		return err
+	}
+	node, err := yaml.Marshal([]CatalogEntry{entry})
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// The new sequence item is appended as text, so comments and formatting
+	// of the existing entries are left untouched.
+	data := existing
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	data = append(data, node...)
+	var appended []CatalogEntry
+	if err := yaml.Unmarshal(data, &appended); err != nil || len(appended) != len(entries)+1 || appended[len(entries)].Name != entry.Name {
+		if len(entries) > 0 {
+			return fmt.Errorf("append to %s: catalog is not a block sequence", path)
+		}
+		data = node
+	}
+	return writeFileAtomically(path, data)
+}
+
+// RemoveEntry deletes the entry named name, compared after Fold, from a YAML
+// or legacy .dat catalog file and returns it. The remaining entries keep their
+// order; ok is false when the file has no such entry.
+func RemoveEntry(path string, name string) (removed CatalogEntry, ok bool, err error) {
+	key := Fold(name)
+	if filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return removed, false, err
+		}
+		var kept []string
+		for _, line := range strings.SplitAfter(string(data), "\n") {
+			entryName, aliases, parsed := ParseEntry(strings.TrimRight(line, "\r\n"))
+			if parsed && !ok && Fold(entryName) == key {
+				removed, ok = CatalogEntry{Name: entryName, Aliases: aliases}, true
+				continue
+			}
+			kept = append(kept, line)
+		}
+		if !ok {
+			return removed, false, nil
+		}
+		return removed, true, writeFileAtomically(path, []byte(strings.Join(kept, "")))
+	}
+
+	entries, err := LoadEntries(path)
+	if err != nil {
+		return removed, false, err
+	}
+	kept := make([]CatalogEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !ok && Fold(entry.Name) == key {
+			removed, ok = entry, true
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if !ok {
+		return removed, false, nil
+	}
+	data, err := yaml.Marshal(kept)
+	if err != nil {
+		return removed, false, err
+	}
+	return removed, true, writeFileAtomically(path, data)
+}
+
+func writeFileAtomically(path string, data []byte) error {
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
 type Result struct {
 	Allowed    []string
 	NotAllowed []string
@@ -100,8 +208,8 @@ func Load(directory string) ([]Food, error) {
 		}
 		for _, entry := range entries {
 			name, aliases := entry.Name, entry.Aliases
-			metaphone := godoublemetaphone.NewShortDoubleMetaphone(name)
-			foods = append(foods, Food{Allowed: folder == "allowed", Name: name, Aliases: aliases, PrimaryShortMetaphone: metaphone.PrimaryShortKey(), AlternateShortMetaphone: metaphone.AlternateShortKey()})
+			metaphone := godoublemetaphone.NewShortDoubleMetaphone(Fold(name))
+			foods = append(foods, Food{Allowed: folder == "allowed", Name: name, Aliases: aliases, Names: entry.Names, PrimaryShortMetaphone: metaphone.PrimaryShortKey(), AlternateShortMetaphone: metaphone.AlternateShortKey(), Source: path})
 		}
 		return nil
 	})
@@ -111,82 +219,220 @@ func Load(directory string) ([]Food, error) {
 	return foods, nil
 }
 
+const (
+	// searchByWord matches query words against the start of any word in a
+	// name or alias, so "milk" finds "Coconut Milk".
+	searchByWord = "searchbyword"
+	// searchBySubstring also matches the query anywhere inside a name or
+	// alias, so "nut" finds "Coconut Milk".
+	searchBySubstring = "searchbysubstring"
+)
+
+// MatchedFood records which search strategies matched one catalog food.
+// Word and Substring are only tried for foods the whole-name prefix missed.
+type MatchedFood struct {
+	Name      string
+	Allowed   bool
+	Text      bool
+	Sound     bool
+	Word      bool
+	Substring bool
+}
+
+// rank orders word and substring search results: whole-name prefix hits
+// first, then word prefix hits, then substring hits.
+func (m MatchedFood) rank() int {
+	switch {
+	case m.Text:
+		return 0
+	case m.Word:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Match returns matched food names in alphabetical order, or for word and
+// substring searches ranked by rank and then alphabetically.
 func Match(foods []Food, query string, typeSearch string) Result {
-	query = strings.ToLower(strings.TrimSpace(query))
+	return DefaultMatchOptions().Match(foods, query, typeSearch)
+}
+
+// Match runs Match with these thresholds.
+func (o MatchOptions) Match(foods []Food, query string, typeSearch string) Result {
+	matches := o.MatchDetails(foods, query, typeSearch)
+	ranked := typeSearch == searchByWord || typeSearch == searchBySubstring
+	if ranked {
+		sort.SliceStable(matches, func(i, j int) bool {
+			if matches[i].rank() != matches[j].rank() {
+				return matches[i].rank() < matches[j].rank()
+			}
+			return matches[i].Name < matches[j].Name
+		})
+	}
+	var allowed, notAllowed []string
+	for _, matched := range matches {
+		if matched.Allowed {
+			allowed = append(allowed, matched.Name)
+		} else {
+			notAllowed = append(notAllowed, matched.Name)
+		}
+	}
+	if ranked {
+		return Result{Allowed: unique(allowed), NotAllowed: unique(notAllowed)}
+	}
+	return Result{Allowed: sortedUnique(allowed), NotAllowed: sortedUnique(notAllowed)}
+}
+
+// MatchDetails runs the same search as Match but reports each matched food
+// with the strategies that found it, in catalog order.
+func MatchDetails(foods []Food, query string, typeSearch string) []MatchedFood {
+	return DefaultMatchOptions().MatchDetails(foods, query, typeSearch)
+}
+
+// MatchDetails runs MatchDetails with these thresholds.
+func (o MatchOptions) MatchDetails(foods []Food, query string, typeSearch string) []MatchedFood {
+	query = Fold(query)
+	singular := Singular(query)
 	sdm := godoublemetaphone.NewShortDoubleMetaphone(query)
-	textSearch, soundSearch := true, true
-	if typeSearch == "searchbytext" {
+	textSearch, soundSearch, wordSearch, substringSearch := true, true, false, false
+	switch typeSearch {
+	case "searchbytext":
 		soundSearch = false
-	}
-	if typeSearch == "searchbysound" {
+	case "searchbysound":
 		textSearch = false
+	case searchByWord:
+		soundSearch, wordSearch = false, true
+	case searchBySubstring:
+		soundSearch, wordSearch, substringSearch = false, true, true
 	}
-	var allowed, notAllowed []string
+	var matches []MatchedFood
 	for _, food := range foods {
-		if textSearch && matchesText(query, food.Name, food.Aliases) {
-			if food.Allowed {
-				allowed = append(allowed, food.Name)
-			} else {
-				notAllowed = append(notAllowed, food.Name)
-			}
-		}
-		if soundSearch && fuzzySoundMatch(query, sdm, food) {
-			if food.Allowed {
-				allowed = append(allowed, food.Name)
-			} else {
-				notAllowed = append(notAllowed, food.Name)
-			}
+		matched := MatchedFood{Name: food.Name, Allowed: food.Allowed}
+		matched.Text = textSearch && matchesText(query, singular, food.Name, food.Aliases)
+		matched.Sound = soundSearch && o.fuzzySoundMatch(query, sdm, food)
+		matched.Word = wordSearch && !matched.Text && matchesWords(query, food.Name, food.Aliases)
+		matched.Substring = substringSearch && !matched.Text && !matched.Word && matchesSubstring(query, singular, food.Name, food.Aliases)
+		if matched.Text || matched.Sound || matched.Word || matched.Substring {
+			matches = append(matches, matched)
 		}
 	}
-	return Result{Allowed: sortedUnique(allowed), NotAllowed: sortedUnique(notAllowed)}
+	return matches
 }
 
 func Covered(foods []Food, query string) bool {
-	result := Match(foods, query, "searchbytextandsound")
+	return DefaultMatchOptions().Covered(foods, query)
+}
+
+// Covered runs Covered with these thresholds.
+func (o MatchOptions) Covered(foods []Food, query string) bool {
+	result := o.Match(foods, query, "searchbytextandsound")
 	return len(result.Allowed) > 0 || len(result.NotAllowed) > 0
 }
 
+// Neighbor is a catalog food close to a query that did not match it.
+type Neighbor struct {
+	Food     Food
+	Distance int
+	Sound    bool
+}
+
+// NearestFoods ranks catalog foods by spelling distance to the query's name,
+// aliases or words, preferring sound-alike candidates on ties. Foods that are
+// neither a sound match nor within half the query length are left out.
+func NearestFoods(foods []Food, query string, limit int) []Neighbor {
+	return DefaultMatchOptions().NearestFoods(foods, query, limit)
+}
+
+// NearestFoods runs NearestFoods with these thresholds.
+func (o MatchOptions) NearestFoods(foods []Food, query string, limit int) []Neighbor {
+	query = Fold(query)
+	if query == "" || limit <= 0 {
+		return nil
+	}
+	sdm := godoublemetaphone.NewShortDoubleMetaphone(query)
+	maxDistance := utf8.RuneCountInString(query) / 2
+	if maxDistance < o.spellingDistanceLimit(query) {
+		maxDistance = o.spellingDistanceLimit(query)
+	}
+
+	var neighbors []Neighbor
+	for _, food := range foods {
+		best := Neighbor{Food: food, Distance: -1}
+		for _, candidate := range append([]string{food.Name}, food.Aliases...) {
+			distances := []int{levenshteinDistance(query, Fold(candidate))}
+			for _, token := range o.searchableTokens(candidate) {
+				distances = append(distances, levenshteinDistance(query, token))
+			}
+			distance := minInt(distances...)
+			sound := metaphoneKeysMatchCandidate(sdm, candidate)
+			if best.Distance < 0 || distance < best.Distance || (distance == best.Distance && sound && !best.Sound) {
+				best.Distance, best.Sound = distance, sound
+			}
+		}
+		if best.Sound || best.Distance <= maxDistance {
+			neighbors = append(neighbors, best)
+		}
+	}
+
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].Distance != neighbors[j].Distance {
+			return neighbors[i].Distance < neighbors[j].Distance
+		}
+		if neighbors[i].Sound != neighbors[j].Sound {
+			return neighbors[i].Sound
+		}
+		return neighbors[i].Food.Name < neighbors[j].Food.Name
+	})
+	if len(neighbors) > limit {
+		neighbors = neighbors[:limit]
+	}
+	return neighbors
+}
+
 // SpellingDistanceAllowed exposes the catalog's spelling threshold for focused tests.
 func SpellingDistanceAllowed(query, candidate string) bool {
-	return spellingDistanceAllowed(query, candidate)
+	return DefaultMatchOptions().SpellingDistanceAllowed(query, candidate)
+}
+
+// SpellingDistanceAllowed runs SpellingDistanceAllowed with these thresholds.
+func (o MatchOptions) SpellingDistanceAllowed(query, candidate string) bool {
+	return o.spellingDistanceAllowed(query, candidate)
 }
 
-func fuzzySoundMatch(query string, queryMetaphone godoublemetaphone.ShortDoubleMetaphone, food Food) bool {
+func (o MatchOptions) fuzzySoundMatch(query string, queryMetaphone godoublemetaphone.ShortDoubleMetaphone, food Food) bool {
 	for _, candidate := range append([]string{food.Name}, food.Aliases...) {
-		if len(searchableTokens(query)) > 1 {
-			if fuzzySoundMultiWordMatch(query, candidate) {
+		if len(o.searchableTokens(query)) > 1 {
+			if o.fuzzySoundMultiWordMatch(query, candidate) {
 				return true
 			}
 			continue
 		}
 
-		if fuzzySoundCandidate(query, queryMetaphone, candidate) {
+		if o.fuzzySoundCandidate(query, queryMetaphone, candidate) {
 			return true
 		}
 	}
 	return false
 }
 
-func fuzzySoundCandidate(query string, queryMetaphone godoublemetaphone.ShortDoubleMetaphone, candidate string) bool {
-	if spellingDistanceAllowed(query, candidate) {
+func (o MatchOptions) fuzzySoundCandidate(query string, queryMetaphone godoublemetaphone.ShortDoubleMetaphone, candidate string) bool {
+	if o.spellingDistanceAllowed(query, candidate) || Singular(query) == Singular(candidate) {
 		return true
 	}
-	for _, token := range searchableTokens(candidate) {
-		if spellingDistanceAllowed(query, token) {
+	for _, token := range o.searchableTokens(candidate) {
+		if o.spellingDistanceAllowed(query, token) {
 			return true
 		}
 	}
 	if !metaphoneKeysMatchCandidate(queryMetaphone, candidate) {
 		return false
 	}
-	limit := spellingDistanceLimit(query)
-	if len(query) > 4 {
-		limit++
-	}
-	if levenshteinDistance(query, strings.ToLower(candidate)) <= limit {
+	limit := o.soundDistanceLimit(query)
+	if levenshteinDistance(query, Fold(candidate)) <= limit {
 		return true
 	}
-	for _, token := range searchableTokens(candidate) {
+	for _, token := range o.searchableTokens(candidate) {
 		if levenshteinDistance(query, token) <= limit {
 			return true
 		}
@@ -194,14 +440,14 @@ func fuzzySoundCandidate(query string, queryMetaphone godoublemetaphone.ShortDou
 	return false
 }
 
-func fuzzySoundMultiWordMatch(query, candidate string) bool {
-	queryTokens := searchableTokens(query)
-	candidateTokens := searchableTokens(candidate)
+func (o MatchOptions) fuzzySoundMultiWordMatch(query, candidate string) bool {
+	queryTokens := o.singularTokens(query)
+	candidateTokens := o.singularTokens(candidate)
 	for _, queryToken := range queryTokens {
 		queryMetaphone := godoublemetaphone.NewShortDoubleMetaphone(queryToken)
 		matched := false
 		for _, candidateToken := range candidateTokens {
-			if fuzzySoundTokenMatch(queryToken, queryMetaphone, candidateToken) {
+			if o.fuzzySoundTokenMatch(queryToken, queryMetaphone, candidateToken) {
 				matched = true
 				break
 			}
@@ -213,27 +459,71 @@ func fuzzySoundMultiWordMatch(query, candidate string) bool {
 	return true
 }
 
-func fuzzySoundTokenMatch(query string, queryMetaphone godoublemetaphone.ShortDoubleMetaphone, candidate string) bool {
-	if spellingDistanceAllowed(query, candidate) {
+func (o MatchOptions) fuzzySoundTokenMatch(query string, queryMetaphone godoublemetaphone.ShortDoubleMetaphone, candidate string) bool {
+	if o.spellingDistanceAllowed(query, candidate) {
 		return true
 	}
-	limit := spellingDistanceLimit(query)
-	if len(query) > 4 {
-		limit++
-	}
-	if levenshteinDistance(query, strings.ToLower(candidate)) <= limit {
+	limit := o.soundDistanceLimit(query)
+	if levenshteinDistance(query, candidate) <= limit {
 		return true
 	}
 	candidateMetaphone := godoublemetaphone.NewShortDoubleMetaphone(candidate)
 	if !metaphoneKeysMatchValues(queryMetaphone, candidateMetaphone) {
 		return false
 	}
-	return levenshteinDistance(query, strings.ToLower(candidate)) <= limit
+	return levenshteinDistance(query, candidate) <= limit
+}
+
+// matchesText reports whether the folded query starts a name or alias, or its
+// singular form is the candidate's leading whole words, so "cherries" finds
+// "Cherry" and "Cherry Tomatoes" but "peas" does not find "Peaches".
+func matchesText(query, singular, name string, aliases []string) bool {
+	for _, candidate := range append([]string{name}, aliases...) {
+		if strings.HasPrefix(Fold(candidate), query) || strings.HasPrefix(Singular(candidate)+" ", singular+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWords reports whether every word of the folded query starts some
+// word of one name or alias, or equals it in singular form, in any order.
+func matchesWords(query string, name string, aliases []string) bool {
+	queryWords := strings.Fields(query)
+	if len(queryWords) == 0 {
+		return false
+	}
+	for _, candidate := range append([]string{name}, aliases...) {
+		candidateWords := strings.FieldsFunc(Fold(candidate), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		matched := true
+		for _, queryWord := range queryWords {
+			if !startsAnyWord(queryWord, candidateWords) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func startsAnyWord(queryWord string, candidateWords []string) bool {
+	singular := singularWord(queryWord)
+	for _, word := range candidateWords {
+		if strings.HasPrefix(word, queryWord) || singularWord(word) == singular {
+			return true
+		}
+	}
+	return false
 }
 
-func matchesText(query, name string, aliases []string) bool {
+// matchesSubstring reports whether the folded query appears anywhere in a
+// name or alias, or its singular form appears there as whole words.
+func matchesSubstring(query, singular, name string, aliases []string) bool {
 	for _, candidate := range append([]string{name}, aliases...) {
-		if strings.HasPrefix(strings.ToLower(candidate), query) {
+		if strings.Contains(Fold(candidate), query) || strings.Contains(" "+Singular(candidate)+" ", " "+singular+" ") {
 			return true
 		}
 	}
@@ -241,7 +531,7 @@ func matchesText(query, name string, aliases []string) bool {
 }
 
 func metaphoneKeysMatchCandidate(query godoublemetaphone.ShortDoubleMetaphone, candidate string) bool {
-	metaphone := godoublemetaphone.NewShortDoubleMetaphone(candidate)
+	metaphone := godoublemetaphone.NewShortDoubleMetaphone(Fold(candidate))
 	return metaphoneKeysMatchValues(query, metaphone)
 }
 
@@ -277,39 +567,53 @@ func validMetaphoneKeys(keys ...uint16) map[uint16]bool {
 	return valid
 }
 
-func spellingDistanceAllowed(query, candidate string) bool {
-	query, candidate = strings.ToLower(strings.TrimSpace(query)), strings.ToLower(strings.TrimSpace(candidate))
+func (o MatchOptions) spellingDistanceAllowed(query, candidate string) bool {
+	query, candidate = Fold(query), Fold(candidate)
 	if query == "" || candidate == "" {
 		return false
 	}
 	if query == candidate {
 		return true
 	}
-	if query[0] != candidate[0] {
+	if first, _ := utf8.DecodeRuneInString(query); !strings.HasPrefix(candidate, string(first)) {
 		return false
 	}
-	return levenshteinDistance(query, candidate) <= spellingDistanceLimit(query)
+	return levenshteinDistance(query, candidate) <= o.spellingDistanceLimit(query)
 }
 
-func searchableTokens(candidate string) []string {
-	fields := strings.FieldsFunc(strings.ToLower(candidate), func(r rune) bool { return r < 'a' || r > 'z' })
-	var result []string
-	for _, field := range fields {
-		if len(field) >= 3 {
-			result = append(result, field)
-		}
+func (o MatchOptions) searchableTokens(candidate string) []string {
+	return tokens(candidate, o.MinTokenLength)
+}
+
+// singularTokens is searchableTokens with each word reduced to its singular.
+func (o MatchOptions) singularTokens(candidate string) []string {
+	words := o.searchableTokens(candidate)
+	for index, word := range words {
+		words[index] = singularWord(word)
 	}
-	return result
+	return words
 }
 
-func spellingDistanceLimit(query string) int {
-	if len(query) <= 4 {
-		return 1
+func (o MatchOptions) spellingDistanceLimit(query string) int {
+	if utf8.RuneCountInString(query) <= o.ShortQueryLength {
+		return o.ShortDistance
 	}
-	return 3
+	return o.LongDistance
 }
 
-func levenshteinDistance(a, b string) int {
+// soundDistanceLimit loosens the spelling limit by SoundBonus for longer
+// queries whose candidate already sounds alike.
+func (o MatchOptions) soundDistanceLimit(query string) int {
+	if utf8.RuneCountInString(query) > o.ShortQueryLength {
+		return o.spellingDistanceLimit(query) + o.SoundBonus
+	}
+	return o.spellingDistanceLimit(query)
+}
+
+// levenshteinDistance counts edits in runes, so a letter outside ASCII is one
+// edit rather than one per byte.
+func levenshteinDistance(first, second string) int {
+	a, b := []rune(first), []rune(second)
 	previous, current := make([]int, len(b)+1), make([]int, len(b)+1)
 	for index := range previous {
 		previous[index] = index
@@ -321,14 +625,14 @@ func levenshteinDistance(a, b string) int {
 			if a[i-1] != b[j-1] {
 				cost = 1
 			}
-			current[j] = min(current[j-1]+1, previous[j]+1, previous[j-1]+cost)
+			current[j] = minInt(current[j-1]+1, previous[j]+1, previous[j-1]+cost)
 		}
 		previous, current = current, previous
 	}
 	return previous[len(b)]
 }
 
-func min(values ...int) int {
+func minInt(values ...int) int {
 	result := values[0]
 	for _, value := range values[1:] {
 		if value < result {
@@ -338,7 +642,8 @@ func min(values ...int) int {
 	return result
 }
 
-func sortedUnique(items []string) []string {
+// unique drops repeated items, keeping the first of each in order.
+func unique(items []string) []string {
 	seen := make(map[string]bool)
 	result := make([]string, 0, len(items))
 	for _, item := range items {
@@ -347,6 +652,11 @@ func sortedUnique(items []string) []string {
 			result = append(result, item)
 		}
 	}
+	return result
+}
+
+func sortedUnique(items []string) []string {
+	result := unique(items)
 	sort.Strings(result)
 	return result
 }
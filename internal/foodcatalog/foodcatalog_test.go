@@ -3,6 +3,7 @@ package foodcatalog
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/CalypsoSys/godoublemetaphone/pkg/godoublemetaphone"
@@ -37,6 +38,86 @@ func TestLoadEntriesYAML(t *testing.T) {
 	}
 }
 
+func TestAppendEntryKeepsExistingYAMLAndDat(t *testing.T) {
+	directory := t.TempDir()
+	yamlPath := filepath.Join(directory, "dairy.yaml")
+	datPath := filepath.Join(directory, "dairy.dat")
+	if err := os.WriteFile(yamlPath, []byte("- name: Milk\n  aliases:\n    - whole milk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(datPath, []byte("Milk\twhole milk\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{yamlPath, datPath} {
+		if err := AppendEntry(path, CatalogEntry{Name: "Ghee"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	yamlData, _ := os.ReadFile(yamlPath)
+	if string(yamlData) != "- name: Milk\n  aliases:\n    - whole milk\n- name: Ghee\n" {
+		t.Fatalf("unexpected YAML %q", yamlData)
+	}
+	datData, _ := os.ReadFile(datPath)
+	if string(datData) != "Milk\twhole milk\nGhee\n" {
+		t.Fatalf("unexpected dat %q", datData)
+	}
+}
+
+func TestAppendEntryKeepsYAMLCommentsAndFormatting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dairy.yaml")
+	original := "# Cultured dairy is reviewed separately.\n- name: Milk   # whole only\n  aliases: [whole milk]\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendEntry(path, CatalogEntry{Name: "Ghee", Aliases: []string{"clarified butter"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != original+"- name: Ghee\n  aliases:\n    - clarified butter\n" {
+		t.Fatalf("unexpected YAML %q", data)
+	}
+
+	emptyPath := filepath.Join(t.TempDir(), "empty.yaml")
+	if err := os.WriteFile(emptyPath, []byte("[]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendEntry(emptyPath, CatalogEntry{Name: "Ghee"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := LoadEntries(emptyPath)
+	if err != nil || len(entries) != 1 || entries[0].Name != "Ghee" {
+		t.Fatalf("unexpected entries %#v err=%v", entries, err)
+	}
+}
+
+func TestRemoveEntryKeepsOtherEntriesInOrder(t *testing.T) {
+	directory := t.TempDir()
+	yamlPath := filepath.Join(directory, "dairy.yaml")
+	datPath := filepath.Join(directory, "dairy.dat")
+	if err := os.WriteFile(yamlPath, []byte("- name: Milk\n- name: Ghee\n  aliases:\n    - clarified butter\n- name: Kefir\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(datPath, []byte("Milk\nGhee\tclarified butter\nKefir\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{yamlPath, datPath} {
+		removed, ok, err := RemoveEntry(path, "GHEE")
+		if err != nil || !ok || removed.Name != "Ghee" || len(removed.Aliases) != 1 {
+			t.Fatalf("%s: removed %#v ok=%v err=%v", path, removed, ok, err)
+		}
+		if _, ok, err := RemoveEntry(path, "Ghee"); ok || err != nil {
+			t.Fatalf("%s: expected a second removal to find nothing, ok=%v err=%v", path, ok, err)
+		}
+	}
+	yamlData, _ := os.ReadFile(yamlPath)
+	datData, _ := os.ReadFile(datPath)
+	if string(yamlData) != "- name: Milk\n- name: Kefir\n" || string(datData) != "Milk\nKefir\n" {
+		t.Fatalf("unexpected catalog files %q %q", yamlData, datData)
+	}
+}
+
 func TestMatchAliasReturnsCanonicalName(t *testing.T) {
 	name := "Chobani Yogurt - All"
 	metaphone := godoublemetaphone.NewShortDoubleMetaphone(name)
@@ -75,3 +156,60 @@ func foodForTest(name string) Food {
 		AlternateShortMetaphone: metaphone.AlternateShortKey(),
 	}
 }
+
+func TestNearestFoodsRanksCloseSpellingsFirst(t *testing.T) {
+	foods := []Food{
+		foodForTest("Apples"),
+		foodForTest("Apricots"),
+		foodForTest("Coconut Milk"),
+	}
+
+	neighbors := NearestFoods(foods, "aples", 2)
+	if len(neighbors) != 1 || neighbors[0].Food.Name != "Apples" || neighbors[0].Distance != 1 {
+		t.Fatalf("neighbors = %#v", neighbors)
+	}
+
+	if neighbors := NearestFoods(foods, "mlik", 3); len(neighbors) == 0 || neighbors[0].Food.Name != "Coconut Milk" {
+		t.Fatalf("expected word-level neighbor for mlik, got %#v", neighbors)
+	}
+}
+
+func TestMatchDetailsReportsStrategies(t *testing.T) {
+	foods := []Food{foodForTest("Pork"), foodForTest("Porchetta")}
+
+	details := MatchDetails(foods, "porc", "")
+	if len(details) != 2 {
+		t.Fatalf("details = %#v", details)
+	}
+	if details[0].Name != "Pork" || details[0].Text || !details[0].Sound {
+		t.Fatalf("expected sound-only Pork match, got %#v", details[0])
+	}
+	if details[1].Name != "Porchetta" || !details[1].Text {
+		t.Fatalf("expected text Porchetta match, got %#v", details[1])
+	}
+}
+
+func TestMatchByWordFindsLaterWordsAndRanksNamePrefixesFirst(t *testing.T) {
+	foods := []Food{foodForTest("Coconut Milk"), foodForTest("Milk"), foodForTest("Almond milk"), foodForTest("Milk Thistle"), foodForTest("Buttermilk")}
+
+	if result := Match(foods, "milk", "searchbytext"); !reflect.DeepEqual(result.Allowed, []string{"Milk", "Milk Thistle"}) {
+		t.Fatalf("expected whole-name prefix text search to be unchanged, got %#v", result.Allowed)
+	}
+	if result := Match(foods, "milk", "searchbyword"); !reflect.DeepEqual(result.Allowed, []string{"Milk", "Milk Thistle", "Almond milk", "Coconut Milk"}) {
+		t.Fatalf("word search = %#v", result.Allowed)
+	}
+	if result := Match(foods, "milk coc", "searchbyword"); !reflect.DeepEqual(result.Allowed, []string{"Coconut Milk"}) {
+		t.Fatalf("expected every query word to start a word in any order, got %#v", result.Allowed)
+	}
+	if result := Match(foods, "milks", "searchbyword"); len(result.Allowed) != 4 {
+		t.Fatalf("expected plural query words to match, got %#v", result.Allowed)
+	}
+	if result := Match(foods, "milk", "searchbysubstring"); !reflect.DeepEqual(result.Allowed, []string{"Milk", "Milk Thistle", "Almond milk", "Coconut Milk", "Buttermilk"}) {
+		t.Fatalf("substring search = %#v", result.Allowed)
+	}
+
+	details := MatchDetails(foods, "nut", "searchbysubstring")
+	if len(details) != 1 || details[0].Name != "Coconut Milk" || details[0].Text || details[0].Word || !details[0].Substring {
+		t.Fatalf("details = %#v", details)
+	}
+}
@@ -0,0 +1,137 @@
+package foodcatalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GoldenQuery pins the results of one search so matcher changes that move
+// common searches show up in review. Allowed and NotAllowed are the exact
+// lists Match returns; MustNotInclude names foods the search must never
+// return, whatever else changes. Type defaults to searchbytextandsound and
+// Lang to DefaultLanguage.
+type GoldenQuery struct {
+	Query          string   `yaml:"query" json:"query"`
+	Type           string   `yaml:"type,omitempty" json:"type,omitempty"`
+	Lang           string   `yaml:"lang,omitempty" json:"lang,omitempty"`
+	Allowed        []string `yaml:"allowed" json:"allowed"`
+	NotAllowed     []string `yaml:"not_allowed" json:"not_allowed"`
+	MustNotInclude []string `yaml:"must_not_include,omitempty" json:"must_not_include,omitempty"`
+}
+
+// LoadGoldenQueries reads a golden file, as JSON for .json paths and YAML
+// otherwise.
+func LoadGoldenQueries(path string) ([]GoldenQuery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var queries []GoldenQuery
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(data, &queries)
+	} else {
+		err = yaml.Unmarshal(data, &queries)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse golden queries %s: %w", path, err)
+	}
+	for index, query := range queries {
+		if strings.TrimSpace(query.Query) == "" {
+			return nil, fmt.Errorf("golden query %d in %s has no query", index+1, path)
+		}
+	}
+	return queries, nil
+}
+
+// WriteGoldenQueries replaces a golden file in the format LoadGoldenQueries
+// reads for its extension.
+func WriteGoldenQueries(path string, queries []GoldenQuery) error {
+	var data []byte
+	var err error
+	if filepath.Ext(path) == ".json" {
+		data, err = json.MarshalIndent(queries, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(queries)
+	}
+	if err != nil {
+		return err
+	}
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
+// RunGolden searches foods the way the golden query describes.
+func (o MatchOptions) RunGolden(foods []Food, query GoldenQuery) Result {
+	typeSearch := query.Type
+	if typeSearch == "" {
+		typeSearch = "searchbytextandsound"
+	}
+	return o.Match(Localize(foods, query.Lang), query.Query, typeSearch)
+}
+
+// CheckGolden describes each way the current results differ from the golden
+// query, or returns nil when they match.
+func (o MatchOptions) CheckGolden(foods []Food, query GoldenQuery) []string {
+	result := o.RunGolden(foods, query)
+	var problems []string
+	if !sameNames(result.Allowed, query.Allowed) {
+		problems = append(problems, fmt.Sprintf("allowed = %s, want %s", formatNames(result.Allowed), formatNames(query.Allowed)))
+	}
+	if !sameNames(result.NotAllowed, query.NotAllowed) {
+		problems = append(problems, fmt.Sprintf("not_allowed = %s, want %s", formatNames(result.NotAllowed), formatNames(query.NotAllowed)))
+	}
+	return append(problems, forbiddenNames(result, query.MustNotInclude)...)
+}
+
+// AcceptGolden returns the golden query with its expected lists replaced by the
+// current results. It fails when the results include a MustNotInclude name,
+// since those are never accepted as intentional.
+func (o MatchOptions) AcceptGolden(foods []Food, query GoldenQuery) (GoldenQuery, error) {
+	result := o.RunGolden(foods, query)
+	if problems := forbiddenNames(result, query.MustNotInclude); len(problems) > 0 {
+		return query, fmt.Errorf("%q: %s", query.Query, strings.Join(problems, "; "))
+	}
+	query.Allowed = append([]string{}, result.Allowed...)
+	query.NotAllowed = append([]string{}, result.NotAllowed...)
+	return query, nil
+}
+
+func forbiddenNames(result Result, mustNotInclude []string) []string {
+	returned := make(map[string]bool)
+	for _, name := range append(append([]string{}, result.Allowed...), result.NotAllowed...) {
+		returned[Fold(name)] = true
+	}
+	var problems []string
+	for _, name := range mustNotInclude {
+		if returned[Fold(name)] {
+			problems = append(problems, fmt.Sprintf("returned %q, which must not be included", name))
+		}
+	}
+	return problems
+}
+
+// sameNames compares result lists in order, treating nil and empty alike.
+func sameNames(got []string, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for index := range got {
+		if got[index] != want[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func formatNames(names []string) string {
+	return "[" + strings.Join(names, ", ") + "]"
+}
@@ -0,0 +1,80 @@
+package foodcatalog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestGoldenQueries checks the real catalog against testdata/golden_queries.yaml.
+// After an intended change, run search_coverage golden --accept and review
+// the golden file diff.
+func TestGoldenQueries(t *testing.T) {
+	foods, err := Load("../../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries, err := LoadGoldenQueries(filepath.Join("testdata", "golden_queries.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := DefaultMatchOptions()
+	for _, query := range queries {
+		query := query
+		t.Run(strings.Join(strings.Fields(query.Type+" "+query.Lang+" "+query.Query), " "), func(t *testing.T) {
+			for _, problem := range options.CheckGolden(foods, query) {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+func TestCheckGoldenReportsChangedAndForbiddenResults(t *testing.T) {
+	foods := []Food{foodForTest("Pork"), foodForTest("Porchetta")}
+	query := GoldenQuery{Query: "porc", Allowed: []string{"Pork"}, MustNotInclude: []string{"porchetta"}}
+
+	problems := DefaultMatchOptions().CheckGolden(foods, query)
+	want := []string{"allowed = [Porchetta, Pork], want [Pork]", `returned "porchetta", which must not be included`}
+	if !reflect.DeepEqual(problems, want) {
+		t.Fatalf("problems = %#v", problems)
+	}
+	if _, err := DefaultMatchOptions().AcceptGolden(foods, query); err == nil {
+		t.Fatal("expected a must-not-include result to block accepting")
+	}
+
+	query.MustNotInclude = []string{"Beef"}
+	accepted, err := DefaultMatchOptions().AcceptGolden(foods, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := DefaultMatchOptions().CheckGolden(foods, accepted); problems != nil {
+		t.Fatalf("accepted golden query still differs: %#v", problems)
+	}
+}
+
+func TestWriteGoldenQueriesRoundTripsYAMLAndJSON(t *testing.T) {
+	queries := []GoldenQuery{{Query: "milk", Type: "searchbyword", Allowed: []string{}, NotAllowed: []string{"Milk"}, MustNotInclude: []string{"Pork"}}}
+	for _, name := range []string{"golden.yaml", "golden.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := WriteGoldenQueries(path, queries); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadGoldenQueries(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded, queries) {
+			t.Fatalf("%s loaded %#v", name, loaded)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "golden.yaml")
+	if err := os.WriteFile(path, []byte("- allowed: [Pork]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGoldenQueries(path); err == nil {
+		t.Fatal("expected an entry without a query to be rejected")
+	}
+}
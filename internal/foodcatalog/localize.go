@@ -0,0 +1,124 @@
+package foodcatalog
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/CalypsoSys/godoublemetaphone/pkg/godoublemetaphone"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultLanguage is the language of catalog names, aliases and the
+	// category labels derived from file names.
+	DefaultLanguage = "en"
+
+	// CategoryLabelsFileName holds translated category labels, keyed by
+	// category file name and then language, in the data folder root.
+	CategoryLabelsFileName = "category_labels.yaml"
+)
+
+// LocalizedName is a food's name and search-only aliases in one language. In
+// YAML it is either a plain name or a mapping with name and aliases.
+type LocalizedName struct {
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases,omitempty"`
+}
+
+func (n *LocalizedName) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		n.Name, n.Aliases = value.Value, nil
+		return nil
+	}
+	type plain LocalizedName
+	return value.Decode((*plain)(n))
+}
+
+func (n LocalizedName) MarshalYAML() (interface{}, error) {
+	if len(n.Aliases) == 0 {
+		return n.Name, nil
+	}
+	type plain LocalizedName
+	return plain(n), nil
+}
+
+// Language reduces a language tag such as "es-MX" or "FR" to the lower-case
+// base language used as a catalog key, or "" when the tag does not parse.
+func Language(tag string) string {
+	parsed, err := language.Parse(strings.TrimSpace(tag))
+	if err != nil {
+		return ""
+	}
+	base, _ := parsed.Base()
+	return base.String()
+}
+
+// Localize returns foods as seen by a user of lang: each translated food is
+// named in lang and keeps its English name and aliases as search aliases, so
+// both languages match. Foods without a translation, and every food for the
+// default language, are returned unchanged.
+func Localize(foods []Food, lang string) []Food {
+	lang = Language(lang)
+	if lang == "" || lang == DefaultLanguage {
+		return foods
+	}
+	localized := make([]Food, len(foods))
+	for index, food := range foods {
+		translation, ok := food.Names[lang]
+		if !ok {
+			localized[index] = food
+			continue
+		}
+		aliases := append(append([]string{food.Name}, food.Aliases...), translation.Aliases...)
+		metaphone := godoublemetaphone.NewShortDoubleMetaphone(Fold(translation.Name))
+		food.Name, food.Aliases = translation.Name, aliases
+		food.PrimaryShortMetaphone, food.AlternateShortMetaphone = metaphone.PrimaryShortKey(), metaphone.AlternateShortKey()
+		localized[index] = food
+	}
+	return localized
+}
+
+// LoadCategoryLabels reads translated category labels, returning an empty map
+// when the file does not exist. Language keys are normalized with Language.
+func LoadCategoryLabels(path string) (map[string]map[string]string, error) {
+	labels := make(map[string]map[string]string)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return labels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for category, translations := range raw {
+		labels[category] = make(map[string]string)
+		for tag, label := range translations {
+			if lang := Language(tag); lang != "" && strings.TrimSpace(label) != "" {
+				labels[category][lang] = strings.TrimSpace(label)
+			}
+		}
+	}
+	return labels, nil
+}
+
+// normalizeNames rekeys translations by base language, dropping unparsable
+// tags, empty names and English, which the entry's own name covers.
+func normalizeNames(names map[string]LocalizedName) map[string]LocalizedName {
+	if len(names) == 0 {
+		return nil
+	}
+	normalized := make(map[string]LocalizedName, len(names))
+	for tag, translation := range names {
+		lang := Language(tag)
+		translation.Name = strings.TrimSpace(translation.Name)
+		if lang != "" && lang != DefaultLanguage && translation.Name != "" {
+			normalized[lang] = translation
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,70 @@
+package foodcatalog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadEntriesReadsTranslatedNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fruits.yaml")
+	contents := "- name: Cherries\n  names:\n    es-MX: Cerezas\n    fr:\n      name: Cerises\n      aliases:\n        - griottes\n    en: Ignored\n"
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := LoadEntries(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := entries[0].Names
+	if len(names) != 2 || names["es"].Name != "Cerezas" || names["fr"].Name != "Cerises" || names["fr"].Aliases[0] != "griottes" {
+		t.Fatalf("names = %#v", names)
+	}
+
+	if err := AppendEntry(path, CatalogEntry{Name: "Plums", Names: map[string]LocalizedName{"es": {Name: "Ciruelas"}}}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "- name: Plums\n  names:\n    es: Ciruelas\n") {
+		t.Fatalf("expected plain translated name in YAML, got %s", data)
+	}
+}
+
+func TestLocalizeNamesFoodsAndMatchesBothLanguages(t *testing.T) {
+	cherries := foodForTest("Cherries")
+	cherries.Names = map[string]LocalizedName{"es": {Name: "Cerezas", Aliases: []string{"guindas"}}}
+	foods := []Food{cherries, foodForTest("Pork")}
+
+	localized := Localize(foods, "es-ES")
+	for _, query := range []string{"cerezas", "guindas", "cherries"} {
+		if result := Match(localized, query, "searchbytext"); len(result.Allowed) != 1 || result.Allowed[0] != "Cerezas" {
+			t.Errorf("Match(%q) = %#v", query, result.Allowed)
+		}
+	}
+	if result := Match(localized, "pork", ""); len(result.Allowed) != 1 || result.Allowed[0] != "Pork" {
+		t.Fatalf("expected untranslated food to keep its English name, got %#v", result.Allowed)
+	}
+	if Localize(foods, "en")[0].Name != "Cherries" || Localize(foods, "de")[0].Name != "Cherries" || foods[0].Name != "Cherries" {
+		t.Fatalf("expected English and untranslated languages to leave foods unchanged")
+	}
+}
+
+func TestLoadCategoryLabelsNormalizesLanguages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), CategoryLabelsFileName)
+	if err := os.WriteFile(path, []byte("herbs_spices:\n  ES: Hierbas y especias\n  fr-CA: \" Herbes et épices \"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	labels, err := LoadCategoryLabels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels["herbs_spices"]["es"] != "Hierbas y especias" || labels["herbs_spices"]["fr"] != "Herbes et épices" {
+		t.Fatalf("labels = %#v", labels)
+	}
+	if missing, err := LoadCategoryLabels(filepath.Join(t.TempDir(), "missing.yaml")); err != nil || len(missing) != 0 {
+		t.Fatalf("expected no labels for a missing file, got %#v, %v", missing, err)
+	}
+}
@@ -0,0 +1,82 @@
+package foodcatalog
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldReplacer spells out letters and typographic marks that NFKD does not
+// decompose into ASCII.
+var foldReplacer = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "ł", "l", "đ", "d", "ð", "d", "þ", "th", "ı", "i",
+	"‘", "'", "’", "'", "‚", "'", "“", "\"", "”", "\"", "„", "\"",
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "−", "-",
+)
+
+// Fold returns the form used to compare food names and queries: compatibility
+// decomposed (NFKD) with diacritics removed, lower-cased, typographic quotes
+// and dashes made ASCII, and whitespace collapsed, so "Crème Fraîche" and
+// "creme fraiche" fold to the same string.
+func Fold(text string) string {
+	if isPlainASCII(text) {
+		return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	}
+
+	var folded strings.Builder
+	for _, r := range norm.NFKD.String(strings.ToLower(text)) {
+		if !unicode.Is(unicode.Mn, r) {
+			folded.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(foldReplacer.Replace(folded.String())), " ")
+}
+
+// Tokens splits folded text into the words used for per-word matching:
+// runs of letters at least three letters long.
+func Tokens(text string) []string {
+	return tokens(text, DefaultMatchOptions().MinTokenLength)
+}
+
+func tokens(text string, minLength int) []string {
+	fields := strings.FieldsFunc(Fold(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	var result []string
+	for _, field := range fields {
+		if utf8.RuneCountInString(field) >= minLength {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// CleanText prepares user-entered text for storage without losing accents:
+// it composes it (NFC), trims it, and drops control and invisible format
+// characters other than line breaks and tabs.
+func CleanText(text string) string {
+	text = strings.TrimSpace(text)
+	if isPlainASCII(text) {
+		return strings.Map(keepPrintable, text)
+	}
+	return strings.Map(keepPrintable, norm.NFC.String(text))
+}
+
+func keepPrintable(r rune) rune {
+	if r == '\n' || r == '\r' || r == '\t' {
+		return r
+	}
+	if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+		return -1
+	}
+	return r
+}
+
+func isPlainASCII(text string) bool {
+	for index := 0; index < len(text); index++ {
+		if text[index] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
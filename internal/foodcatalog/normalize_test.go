@@ -0,0 +1,59 @@
+package foodcatalog
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/CalypsoSys/godoublemetaphone/pkg/godoublemetaphone"
+)
+
+func TestFoldRemovesDiacriticsAndTypography(t *testing.T) {
+	cases := map[string]string{
+		"Crème  Fraîche":      "creme fraiche",
+		"Jalapeño":            "jalapeno",
+		"Smørrebrød":          "smorrebrod",
+		"Weißwurst":           "weisswurst",
+		"Ｔｏｆｕ":                "tofu",
+		"Grandma’s Jam – Fig": "grandma's jam - fig",
+		"  Plain Text ":       "plain text",
+	}
+	for input, want := range cases {
+		if got := Fold(input); got != want {
+			t.Errorf("Fold(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestTokensKeepsAccentedWords(t *testing.T) {
+	if got := Tokens("Crème fraîche, ½ cup of açaí"); !reflect.DeepEqual(got, []string{"creme", "fraiche", "cup", "acai"}) {
+		t.Fatalf("tokens = %#v", got)
+	}
+}
+
+func TestCleanTextKeepsAccentsAndDropsInvisibleCharacters(t *testing.T) {
+	if got := CleanText("  Cre\u0300me\u200b frai\u0302che\u0007\n"); got != "Crème fraîche" {
+		t.Fatalf("CleanText = %q", got)
+	}
+	if got := CleanText("line one\nline two\ttab"); got != "line one\nline two\ttab" {
+		t.Fatalf("CleanText changed line breaks: %q", got)
+	}
+}
+
+func TestMatchFoldsAccentsInQueriesAndNames(t *testing.T) {
+	name := "Crème Fraîche"
+	metaphone := godoublemetaphone.NewShortDoubleMetaphone(Fold(name))
+	foods := []Food{
+		{Allowed: true, Name: name, PrimaryShortMetaphone: metaphone.PrimaryShortKey(), AlternateShortMetaphone: metaphone.AlternateShortKey()},
+		foodForTest("Jalapeno"),
+	}
+
+	if result := Match(foods, "creme fraiche", "searchbytext"); len(result.Allowed) != 1 || result.Allowed[0] != name {
+		t.Fatalf("expected ASCII query to match accented name, got %#v", result.Allowed)
+	}
+	if result := Match(foods, "Jalapeño", "searchbytext"); len(result.Allowed) != 1 || result.Allowed[0] != "Jalapeno" {
+		t.Fatalf("expected accented query to match ASCII name, got %#v", result.Allowed)
+	}
+	if neighbors := NearestFoods(foods, "crème fraiche", 1); len(neighbors) != 1 || neighbors[0].Food.Name != name || neighbors[0].Distance != 0 {
+		t.Fatalf("neighbors = %#v", neighbors)
+	}
+}
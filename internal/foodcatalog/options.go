@@ -0,0 +1,46 @@
+package foodcatalog
+
+import "errors"
+
+// MatchOptions holds the spelling and sound thresholds used by Match.
+// Lengths count the runes of the folded query.
+type MatchOptions struct {
+	// ShortQueryLength is the longest query that uses ShortDistance.
+	ShortQueryLength int `json:"shortQueryLength"`
+	// ShortDistance is the edit distance allowed for short queries.
+	ShortDistance int `json:"shortDistance"`
+	// LongDistance is the edit distance allowed for longer queries.
+	LongDistance int `json:"longDistance"`
+	// SoundBonus is added to LongDistance when a candidate sounds alike.
+	SoundBonus int `json:"soundBonus"`
+	// MinTokenLength is the shortest word compared on its own.
+	MinTokenLength int `json:"minTokenLength"`
+}
+
+// DefaultMatchOptions returns the thresholds the catalog was tuned with.
+func DefaultMatchOptions() MatchOptions {
+	return MatchOptions{
+		ShortQueryLength: 4,
+		ShortDistance:    1,
+		LongDistance:     3,
+		SoundBonus:       1,
+		MinTokenLength:   3,
+	}
+}
+
+// Validate rejects negative thresholds and a token length below one.
+func (o MatchOptions) Validate() error {
+	switch {
+	case o.ShortQueryLength < 0:
+		return errors.New("shortQueryLength must not be negative")
+	case o.ShortDistance < 0:
+		return errors.New("shortDistance must not be negative")
+	case o.LongDistance < 0:
+		return errors.New("longDistance must not be negative")
+	case o.SoundBonus < 0:
+		return errors.New("soundBonus must not be negative")
+	case o.MinTokenLength < 1:
+		return errors.New("minTokenLength must be at least 1")
+	}
+	return nil
+}
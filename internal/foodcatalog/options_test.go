@@ -0,0 +1,64 @@
+package foodcatalog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchOptionsChangeSpellingAndTokenThresholds(t *testing.T) {
+	foods := []Food{foodForTest("Pork"), foodForTest("Bok Choy"), foodForTest("Kale")}
+
+	if result := Match(foods, "porc", "searchbytextandsound"); !reflect.DeepEqual(result.Allowed, []string{"Pork"}) {
+		t.Fatalf("default = %#v", result.Allowed)
+	}
+	strict := DefaultMatchOptions()
+	strict.ShortDistance = 0
+	if result := strict.Match(foods, "porc", "searchbysound"); len(result.Allowed) != 0 {
+		t.Fatalf("expected no sound match with distance 0, got %#v", result.Allowed)
+	}
+
+	if result := Match(foods, "chy", "searchbysound"); !reflect.DeepEqual(result.Allowed, []string{"Bok Choy"}) {
+		t.Fatalf("expected a word-level sound match by default, got %#v", result.Allowed)
+	}
+	long := DefaultMatchOptions()
+	long.MinTokenLength = 5
+	if result := long.Match(foods, "chy", "searchbysound"); len(result.Allowed) != 0 {
+		t.Fatalf("expected words shorter than MinTokenLength to be skipped, got %#v", result.Allowed)
+	}
+}
+
+func TestMatchOptionsValidate(t *testing.T) {
+	if err := DefaultMatchOptions().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, options := range []MatchOptions{{LongDistance: -1, MinTokenLength: 3}, {MinTokenLength: 0}} {
+		if err := options.Validate(); err == nil {
+			t.Fatalf("expected %#v to be rejected", options)
+		}
+	}
+}
+
+func TestMatchOptionsCountRunesAndReachNearestFoods(t *testing.T) {
+	strict := DefaultMatchOptions()
+	strict.ShortDistance, strict.LongDistance = 0, 1
+	// "γαλα" is four runes but eight bytes, so it is a short query.
+	if strict.SpellingDistanceAllowed("γαλα", "γαλο") {
+		t.Fatal("expected a four-rune query to use ShortDistance")
+	}
+	if !strict.SpellingDistanceAllowed("γαλατα", "γαλατο") {
+		t.Fatal("expected a six-rune query to use LongDistance")
+	}
+
+	foods := []Food{foodForTest("Pork")}
+	if SpellingDistanceAllowed("prok", "pork") || len(NearestFoods(foods, "pxxxxk", 1)) != 0 {
+		t.Fatal("unexpected default thresholds")
+	}
+	loose := DefaultMatchOptions()
+	loose.ShortDistance, loose.LongDistance = 2, 4
+	if !loose.SpellingDistanceAllowed("prok", "pork") {
+		t.Fatal("expected SpellingDistanceAllowed to use the options' ShortDistance")
+	}
+	if neighbors := loose.NearestFoods(foods, "pxxxxk", 1); len(neighbors) != 1 || neighbors[0].Distance != 4 {
+		t.Fatalf("expected NearestFoods to use the options' LongDistance, got %#v", neighbors)
+	}
+}
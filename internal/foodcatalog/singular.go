@@ -0,0 +1,87 @@
+package foodcatalog
+
+import (
+	"strings"
+	"unicode"
+)
+
+// irregularSingulars lists plurals the suffix rules below get wrong, either
+// because the singular ends in -f/-fe or because it already ends in -ie or -oe.
+// It also maps uncountable words ending in -s, such as "molasses", to
+// themselves so no suffix is stripped.
+var irregularSingulars = map[string]string{
+	"leaves":    "leaf",
+	"loaves":    "loaf",
+	"halves":    "half",
+	"calves":    "calf",
+	"knives":    "knife",
+	"shelves":   "shelf",
+	"geese":     "goose",
+	"mice":      "mouse",
+	"cookies":   "cookie",
+	"brownies":  "brownie",
+	"smoothies": "smoothie",
+	"veggies":   "veggie",
+	"pies":      "pie",
+	"calories":  "calorie",
+	"shoes":     "shoe",
+	"molasses":  "molasses",
+}
+
+// Singular folds text and reduces each English plural word to its singular,
+// so "Blueberries", "blueberry" and "Tomatoes"/"tomato" share one form. It is
+// applied to catalog names and queries alike; words that are not plurals,
+// such as "hummus" or "swiss", are left as they are.
+func Singular(text string) string {
+	words := strings.Fields(Fold(text))
+	for index, word := range words {
+		words[index] = singularWord(word)
+	}
+	return strings.Join(words, " ")
+}
+
+func singularWord(word string) string {
+	if len(word) <= 3 || strings.IndexFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+		return word
+	}
+	if singular, ok := irregularSingulars[word]; ok {
+		return singular
+	}
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "oes"),
+		strings.HasSuffix(word, "sses"),
+		strings.HasSuffix(word, "shes"),
+		strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "xes"),
+		strings.HasSuffix(word, "zzes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// Duplicates groups catalog foods whose names only differ by case, accents
+// or plural form, such as "Cherries" and "Cherry", in catalog order.
+func Duplicates(foods []Food) [][]Food {
+	groups := make(map[string][]Food)
+	var keys []string
+	for _, food := range foods {
+		key := Singular(food.Name)
+		if _, seen := groups[key]; !seen {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], food)
+	}
+	var duplicates [][]Food
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			duplicates = append(duplicates, groups[key])
+		}
+	}
+	return duplicates
+}
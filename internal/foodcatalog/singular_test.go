@@ -0,0 +1,93 @@
+package foodcatalog
+
+import "testing"
+
+func TestSingularReducesEnglishPlurals(t *testing.T) {
+	cases := map[string]string{
+		"Berries":             "berry",
+		"Tomatoes":            "tomato",
+		"Bay Leaves":          "bay leaf",
+		"Peaches":             "peach",
+		"Olives":              "olive",
+		"Cookies":             "cookie",
+		"Brazil Nuts":         "brazil nut",
+		"Hummus":              "hummus",
+		"Blackstrap Molasses": "blackstrap molasses",
+		"Swiss Chard":         "swiss chard",
+		"Peas":                "pea",
+		"Fig":                 "fig",
+		"Açaí Berries":        "acai berry",
+		"Grandma's Apples":    "grandma's apple",
+	}
+	for input, want := range cases {
+		if got := Singular(input); got != want {
+			t.Errorf("Singular(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestMatchFindsSingularAndPluralForms(t *testing.T) {
+	foods := []Food{foodForTest("Cherries"), foodForTest("Tomato"), foodForTest("Bay Leaf"), foodForTest("Blueberries")}
+
+	for query, want := range map[string]string{
+		"cherry":     "Cherries",
+		"tomatoes":   "Tomato",
+		"bay leaves": "Bay Leaf",
+		"blueberry":  "Blueberries",
+		"blueber":    "Blueberries",
+		"cherries":   "Cherries",
+	} {
+		result := Match(foods, query, "searchbytext")
+		if len(result.Allowed) != 1 || result.Allowed[0] != want {
+			t.Errorf("Match(%q) = %#v, want %s", query, result.Allowed, want)
+		}
+	}
+	if result := Match(foods, "leaves", "searchbysound"); len(result.Allowed) != 1 || result.Allowed[0] != "Bay Leaf" {
+		t.Fatalf("expected plural word to sound-match a singular word, got %#v", result.Allowed)
+	}
+}
+
+// TestSingularMatchesWholeWordsInRealCatalog guards against singular forms
+// matching as loose prefixes, where "peas" would find "Peaches".
+func TestSingularMatchesWholeWordsInRealCatalog(t *testing.T) {
+	foods, err := Load("../../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		query   string
+		want    []string
+		exclude []string
+	}{
+		{"peas", []string{"Peas"}, []string{"Peaches", "Peanut oil", "Peanuts"}},
+		{"nuts", []string{"Nut Butters", "Nut Oil"}, []string{"Nutmeg", "Nutritional Yeast"}},
+		{"oats", []string{"Oats"}, []string{"Oatmeal"}},
+	} {
+		for _, typeSearch := range []string{"searchbytext", searchByWord, searchBySubstring} {
+			result := Match(foods, test.query, typeSearch)
+			returned := make(map[string]bool)
+			for _, name := range append(append([]string{}, result.Allowed...), result.NotAllowed...) {
+				returned[name] = true
+			}
+			for _, name := range test.want {
+				if !returned[name] {
+					t.Errorf("Match(%q, %s) = %v %v, want %s", test.query, typeSearch, result.Allowed, result.NotAllowed, name)
+				}
+			}
+			for _, name := range test.exclude {
+				if returned[name] {
+					t.Errorf("Match(%q, %s) returned %s", test.query, typeSearch, name)
+				}
+			}
+		}
+	}
+}
+
+func TestDuplicatesGroupsPluralVariants(t *testing.T) {
+	foods := []Food{foodForTest("Cherries"), foodForTest("Pork"), foodForTest("cherry"), foodForTest("Beets"), foodForTest("Beet")}
+
+	groups := Duplicates(foods)
+	if len(groups) != 2 || len(groups[0]) != 2 || groups[0][0].Name != "Cherries" || groups[0][1].Name != "cherry" || groups[1][0].Name != "Beets" {
+		t.Fatalf("groups = %#v", groups)
+	}
+}